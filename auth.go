@@ -0,0 +1,103 @@
+package zen
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	authorization   = "Authorization"
+	wwwAuthenticate = "WWW-Authenticate"
+
+	bearerPrefix = "Bearer "
+)
+
+// BasicAuth return a middleware which authenticate requests with basic auth,
+// lookup return the expected password and the principal of user,
+// passwords are compared in constant time
+func BasicAuth(realm string, lookup func(user string) (password string, principal interface{}, ok bool)) HandlerFunc {
+	assert(lookup != nil, "basic auth lookup cannot be nil")
+	challenge := "Basic realm=" + strconv.Quote(realm)
+
+	return func(c *Context) {
+		user, password, ok := c.Req.BasicAuth()
+		if !ok {
+			c.unauthorized(challenge)
+			return
+		}
+
+		expected, principal, found := lookup(user)
+		// compare even if user is unknown, so that timing does not reveal it
+		if !secureCompare(password, expected) || !found {
+			c.unauthorized(challenge)
+			return
+		}
+		c.SetPrincipal(principal)
+	}
+}
+
+// BasicAuthUsers return a BasicAuth middleware with a static user to password map,
+// the principal is the user name
+func BasicAuthUsers(realm string, users map[string]string) HandlerFunc {
+	return BasicAuth(realm, func(user string) (string, interface{}, bool) {
+		password, ok := users[user]
+		return password, user, ok
+	})
+}
+
+// BearerAuth return a middleware which authenticate requests with bearer token,
+// validate return the principal of a valid token
+func BearerAuth(realm string, validate func(token string) (principal interface{}, ok bool)) HandlerFunc {
+	assert(validate != nil, "bearer auth validate cannot be nil")
+	challenge := "Bearer realm=" + strconv.Quote(realm)
+
+	return func(c *Context) {
+		token, ok := c.bearerToken()
+		if !ok {
+			c.unauthorized(challenge)
+			return
+		}
+
+		principal, ok := validate(token)
+		if !ok {
+			c.unauthorized(challenge + `, error="invalid_token"`)
+			return
+		}
+		c.SetPrincipal(principal)
+	}
+}
+
+// Principal return the authenticated principal of request
+func (c *Context) Principal() interface{} {
+	return c.principal
+}
+
+// SetPrincipal set the authenticated principal of request
+func (c *Context) SetPrincipal(principal interface{}) {
+	c.principal = principal
+}
+
+// bearerToken return the token of request's Authorization header
+func (c *Context) bearerToken() (string, bool) {
+	auth := c.Req.Header.Get(authorization)
+	if len(auth) <= len(bearerPrefix) || !strings.EqualFold(auth[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(auth[len(bearerPrefix):]), true
+}
+
+// unauthorized respond 401 with challenge
+func (c *Context) unauthorized(challenge string) {
+	c.rw.Header().Set(wwwAuthenticate, challenge)
+	http.Error(c.rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
+
+// secureCompare compare a and b in constant time, regardless of their length
+func secureCompare(a, b string) bool {
+	ha := sha256.Sum256([]byte(a))
+	hb := sha256.Sum256([]byte(b))
+	return subtle.ConstantTimeCompare(ha[:], hb[:]) == 1
+}
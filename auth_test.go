@@ -0,0 +1,85 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuthUsers(t *testing.T) {
+	tests := []struct {
+		name          string
+		user          string
+		password      string
+		wantCode      int
+		wantPrincipal interface{}
+	}{
+		{"valid", "zen", "secret", http.StatusOK, "zen"},
+		{"wrong password", "zen", "wrong", http.StatusUnauthorized, nil},
+		{"unknown user", "who", "secret", http.StatusUnauthorized, nil},
+		{"missing", "", "", http.StatusUnauthorized, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal interface{}
+			s := New()
+			s.Get("/", Chain(BasicAuthUsers("zen", map[string]string{"zen": "secret"}), func(c *Context) {
+				principal = c.Principal()
+				c.RawStr("ok")
+			}))
+
+			req := httptest.NewRequest(GET, "/", nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if principal != tt.wantPrincipal {
+				t.Errorf("Principal() = %v, want %v", principal, tt.wantPrincipal)
+			}
+			if tt.wantCode == http.StatusUnauthorized && rw.Header().Get(wwwAuthenticate) != `Basic realm="zen"` {
+				t.Errorf("WWW-Authenticate = %q", rw.Header().Get(wwwAuthenticate))
+			}
+		})
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		auth          string
+		wantCode      int
+		wantChallenge string
+	}{
+		{"valid", "Bearer token", http.StatusOK, ""},
+		{"lower case scheme", "bearer token", http.StatusOK, ""},
+		{"invalid", "Bearer other", http.StatusUnauthorized, `Bearer realm="api", error="invalid_token"`},
+		{"missing", "", http.StatusUnauthorized, `Bearer realm="api"`},
+		{"basic", "Basic dG9rZW4=", http.StatusUnauthorized, `Bearer realm="api"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Filter(BearerAuth("api", func(token string) (interface{}, bool) {
+				return "user", token == "token"
+			}))
+			s.Get("/", func(c *Context) {
+				c.RawStr(c.Principal().(string))
+			})
+
+			req := httptest.NewRequest(GET, "/", nil)
+			req.Header.Set(authorization, tt.auth)
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got := rw.Header().Get(wwwAuthenticate); got != tt.wantChallenge {
+				t.Errorf("WWW-Authenticate = %q, want %q", got, tt.wantChallenge)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package zen
+
+import (
+	"encoding/asn1"
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// commonly used form mime-types
+const (
+	applicationForm = "application/x-www-form-urlencoded"
+	multipartForm   = "multipart/form-data"
+)
+
+var (
+	// ErrUnsupportedMediaType is returned by Bind when no binder is registered for request's Content-Type
+	ErrUnsupportedMediaType = errors.New("zen: unsupported media type")
+	// ErrBindTarget is returned when values are bound into anything but a pointer to struct
+	ErrBindTarget = errors.New("zen: bind target must be a pointer to struct")
+)
+
+type (
+	// Binder decode request into v
+	Binder interface {
+		Bind(c *Context, v interface{}) error
+	}
+
+	// BinderFunc adapt a function into Binder
+	BinderFunc func(c *Context, v interface{}) error
+)
+
+// Bind implement Binder
+func (f BinderFunc) Bind(c *Context, v interface{}) error {
+	return f(c, v)
+}
+
+// RegisterBinder register b as the binder of mediaType for Context.Bind,
+// which replaces the binder registered for the same media type
+func (s *Server) RegisterBinder(mediaType string, b Binder) {
+	assert(b != nil, "binder cannot be nil")
+	s.binders[mediaType] = b
+}
+
+// registerDefaultBinders register JSON, XML, form and ASN.1 binders
+func (s *Server) registerDefaultBinders() {
+	s.binders = make(map[string]Binder)
+	jsonBinder := BinderFunc((*Context).bindJSON)
+	xmlBinder := BinderFunc((*Context).bindXML)
+	formBinder := BinderFunc((*Context).bindForm)
+	s.RegisterBinder(applicationJSON, jsonBinder)
+	s.RegisterBinder(applicationXML, xmlBinder)
+	s.RegisterBinder(textXML, xmlBinder)
+	s.RegisterBinder(applicationForm, formBinder)
+	s.RegisterBinder(multipartForm, formBinder)
+	s.RegisterBinder(applicationASN1, BinderFunc((*Context).bindASN1))
+}
+
+// Bind decode request into v with the binder registered for request's Content-Type,
+// then validate v the same way as ParseValidateForm. Requests without Content-Type,
+// such as GET requests, are bound from query string as a form.
+func (c *Context) Bind(v interface{}) error {
+	binder, err := c.binder()
+	if err != nil {
+		return err
+	}
+	return c.validateBound(v, binder.Bind(c, v))
+}
+
+// binder return the binder of request's Content-Type
+func (c *Context) binder() (Binder, error) {
+	ct := c.Req.Header.Get(contentType)
+	if ct == "" {
+		return BinderFunc((*Context).bindForm), nil
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, ErrUnsupportedMediaType
+	}
+	binder, ok := c.server.binders[mediaType]
+	if !ok {
+		return nil, ErrUnsupportedMediaType
+	}
+	return binder, nil
+}
+
+// BindParams scan url params into v's fields by param tag
+func (c *Context) BindParams(v interface{}) error {
+	return bindValues(v, paramTagName, func(key string) ([]string, bool) {
+		value, ok := c.params.lookup(key)
+		return []string{value}, ok
+	}, func(prefix string) bool {
+		for _, p := range c.params {
+			if strings.HasPrefix(p.Key, prefix) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// BindQuery scan query string into v's fields by query tag, request's body is untouched
+func (c *Context) BindQuery(v interface{}) error {
+	return bindValues(v, queryTagName, valuesGetter(c.queryValues()), valuesPrefixed(c.queryValues()))
+}
+
+// BindHeader scan request headers into v's fields by header tag
+func (c *Context) BindHeader(v interface{}) error {
+	return bindValues(v, headerTagName, func(key string) ([]string, bool) {
+		values := c.Req.Header[http.CanonicalHeaderKey(key)]
+		return values, len(values) > 0
+	}, func(prefix string) bool {
+		for key := range c.Req.Header {
+			if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// bindASN1 decode request's asn1 body into v
+func (c *Context) bindASN1(v interface{}) error {
+	if err := c.prepareBody(); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(c.Req.Body)
+	if err != nil {
+		return c.bodyError(err)
+	}
+	_, err = asn1.Unmarshal(data, v)
+	return err
+}
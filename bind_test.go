@@ -0,0 +1,270 @@
+package zen
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindInput struct {
+	Name string `form:"name" json:"name" xml:"name" valid:"^[a-z]+$" msg:"illegal name"`
+	Age  int    `form:"age" json:"age" xml:"age"`
+}
+
+func TestContext_Bind(t *testing.T) {
+	asn1Body, _ := asn1.Marshal(bindInput{Name: "zen", Age: 3})
+	tests := []struct {
+		name        string
+		method      string
+		url         string
+		contentType string
+		body        string
+		wantErr     string
+		want        bindInput
+	}{
+		{"json", POST, "/", "application/json; charset=utf-8", `{"name":"zen","age":3}`, "", bindInput{"zen", 3}},
+		{"xml", POST, "/", "text/xml", `<bindInput><name>zen</name><age>3</age></bindInput>`, "", bindInput{"zen", 3}},
+		{"form", POST, "/", applicationForm, "name=zen&age=3", "", bindInput{"zen", 3}},
+		{"multipart", POST, "/", "multipart/form-data; boundary=zen", "--zen\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\nzen\r\n--zen\r\nContent-Disposition: form-data; name=\"age\"\r\n\r\n3\r\n--zen--\r\n", "", bindInput{"zen", 3}},
+		{"asn1", POST, "/", applicationASN1, string(asn1Body), "", bindInput{"zen", 3}},
+		{"query", GET, "/?name=zen&age=3", "", "", "", bindInput{"zen", 3}},
+		{"invalid", POST, "/", applicationJSON, `{"name":"ZEN","age":3}`, "illegal name", bindInput{"ZEN", 3}},
+		{"unsupported", POST, "/", "text/csv", "name,age", ErrUnsupportedMediaType.Error(), bindInput{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			req := httptest.NewRequest(tt.method, tt.url, bytes.NewReader([]byte(tt.body)))
+			if tt.contentType != "" {
+				req.Header.Set(contentType, tt.contentType)
+			}
+			c := s.getContext(httptest.NewRecorder(), req)
+			defer s.putBackContext(c)
+
+			var got bindInput
+			err := c.Bind(&got)
+			if (err == nil && tt.wantErr != "") || (err != nil && err.Error() != tt.wantErr) {
+				t.Fatalf("Bind() error = %v, wantErr %q", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_RegisterBinder(t *testing.T) {
+	s := New()
+	s.RegisterBinder("text/csv", BinderFunc(func(c *Context, v interface{}) error {
+		input := v.(*bindInput)
+		input.Name = strings.Split(c.Req.Header.Get("X-Row"), ",")[0]
+		return nil
+	}))
+	req := httptest.NewRequest(POST, "/", nil)
+	req.Header.Set(contentType, "text/csv")
+	req.Header.Set("X-Row", "zen,3")
+	c := s.getContext(httptest.NewRecorder(), req)
+	defer s.putBackContext(c)
+
+	var got bindInput
+	if err := c.Bind(&got); err != nil || got.Name != "zen" {
+		t.Errorf("Bind() = %+v, %v, want name zen", got, err)
+	}
+}
+
+func TestContext_BindParams_Query_Header(t *testing.T) {
+	type request struct {
+		ID      uint64  `param:"id"`
+		Page    int     `query:"page"`
+		Ratio   float64 `query:"ratio"`
+		Token   string  `header:"x-token"`
+		Debug   bool    `query:"debug" header:"X-Debug"`
+		Ignored string
+	}
+
+	var got request
+	var errs []error
+	s := New()
+	s.Get("/users/:id", func(c *Context) {
+		errs = append(errs, c.BindParams(&got), c.BindQuery(&got), c.BindHeader(&got))
+		c.RawStr("ok")
+	})
+	req := httptest.NewRequest(GET, "/users/42?page=2&ratio=0.5", nil)
+	req.Header.Set("X-Token", "secret")
+	req.Header.Set("X-Debug", "true")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("bind error = %v", err)
+		}
+	}
+	want := request{ID: 42, Page: 2, Ratio: 0.5, Token: "secret", Debug: true}
+	if got != want {
+		t.Errorf("bound = %+v, want %+v", got, want)
+	}
+}
+
+type bindNode struct {
+	Name   string    `query:"name" header:"x-name"`
+	Parent *bindNode `query:"parent" header:"x-parent"`
+}
+
+func TestContext_Bind_SelfReference(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  bindNode
+	}{
+		{"leaf", "name=a", bindNode{Name: "a"}},
+		{"parents", "name=a&parent.parent.name=c", bindNode{Name: "a", Parent: &bindNode{Parent: &bindNode{Name: "c"}}}},
+	}
+
+	var got bindNode
+	var errs []error
+	s := New()
+	s.Get("/", func(c *Context) {
+		got = bindNode{}
+		errs = []error{c.BindQuery(&got), c.BindHeader(&got)}
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(GET, "/?"+tt.query, nil)
+			req.Header.Set("X-Name", tt.want.Name)
+			s.ServeHTTP(httptest.NewRecorder(), req)
+
+			for _, err := range errs {
+				if err != nil {
+					t.Fatalf("bind error = %v", err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bound = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type bindLevel int
+
+func (l *bindLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("unknown level")
+	}
+	return nil
+}
+
+type bindAddress struct {
+	City string `form:"city"`
+	Zip  *int   `form:"zip"`
+}
+
+type bindAudit struct {
+	Author string `form:"author"`
+}
+
+func TestContext_BindForm_Types(t *testing.T) {
+	type request struct {
+		bindAudit
+		Tags     []string      `form:"tag"`
+		IDs      []int         `form:"id"`
+		Limit    *int          `form:"limit"`
+		Missing  *string       `form:"missing"`
+		Born     time.Time     `form:"born" layout:"2006-01-02"`
+		At       time.Time     `form:"at"`
+		TTL      time.Duration `form:"ttl"`
+		Level    bindLevel     `form:"level"`
+		Levels   []bindLevel   `form:"levels"`
+		Address  bindAddress   `form:"address"`
+		Shipping *bindAddress  `form:"shipping"`
+		Billing  *bindAddress  `form:"billing"`
+	}
+
+	query := "tag=a&tag=b&id=1&id=2&limit=10&born=1990-05-17&at=2017-01-02T15:04:05Z&ttl=1m30s" +
+		"&level=high&levels=low&levels=high&address.city=Paris&address.zip=75001&shipping.city=Lyon&author=bob"
+	var got request
+	var err error
+	s := New()
+	s.Get("/", func(c *Context) {
+		err = c.Bind(&got)
+	})
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/?"+query, nil))
+	if err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got.Tags, []string{"a", "b"}) || !reflect.DeepEqual(got.IDs, []int{1, 2}) {
+		t.Errorf("slices = %v %v", got.Tags, got.IDs)
+	}
+	if got.Limit == nil || *got.Limit != 10 || got.Missing != nil {
+		t.Errorf("pointers = %v %v", got.Limit, got.Missing)
+	}
+	if !got.Born.Equal(time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)) || !got.At.Equal(time.Date(2017, 1, 2, 15, 4, 5, 0, time.UTC)) {
+		t.Errorf("times = %v %v", got.Born, got.At)
+	}
+	if got.TTL != 90*time.Second {
+		t.Errorf("TTL = %v", got.TTL)
+	}
+	if got.Level != 2 || !reflect.DeepEqual(got.Levels, []bindLevel{1, 2}) {
+		t.Errorf("levels = %v %v", got.Level, got.Levels)
+	}
+	if got.Address.City != "Paris" || got.Address.Zip == nil || *got.Address.Zip != 75001 {
+		t.Errorf("Address = %+v", got.Address)
+	}
+	if got.Shipping == nil || got.Shipping.City != "Lyon" || got.Billing != nil {
+		t.Errorf("Shipping = %+v, Billing = %+v", got.Shipping, got.Billing)
+	}
+	if got.Author != "bob" {
+		t.Errorf("Author = %q", got.Author)
+	}
+}
+
+func TestContext_BindForm_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		input interface{}
+	}{
+		{"int overflow", "n=300", &struct {
+			N int8 `form:"n"`
+		}{}},
+		{"bad time", "t=yesterday", &struct {
+			T time.Time `form:"t"`
+		}{}},
+		{"bad duration", "d=soon", &struct {
+			D time.Duration `form:"d"`
+		}{}},
+		{"bad slice element", "n=1&n=x", &struct {
+			N []int `form:"n"`
+		}{}},
+		{"text unmarshaler", "l=medium", &struct {
+			L bindLevel `form:"l"`
+		}{}},
+		{"nested", "a.zip=x", &struct {
+			A bindAddress `form:"a"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			s := New()
+			s.Get("/", func(c *Context) {
+				err = c.Bind(tt.input)
+			})
+			s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/?"+tt.query, nil))
+			if err == nil {
+				t.Error("Bind() error = nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package zen
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentEncoding = "Content-Encoding"
+
+	// defaultMaxDecompressedSize is the default limit of a decompressed request body
+	defaultMaxDecompressedSize = 10 << 20
+	// defaultMaxMultipartMemory is the default memory threshold of multipart form parsing
+	defaultMaxMultipartMemory = 32 << 10
+)
+
+var (
+	// ErrBodyTooLarge is returned when request body exceeds the max body bytes
+	ErrBodyTooLarge = errors.New("zen: request body too large")
+	// ErrDecompressedBodyTooLarge is returned when a decompressed request body exceeds the server's limit
+	ErrDecompressedBodyTooLarge = errors.New("zen: decompressed request body too large")
+	// ErrUnsupportedContentEncoding is returned when request's Content-Encoding can not be decoded
+	ErrUnsupportedContentEncoding = errors.New("zen: unsupported content encoding")
+)
+
+// SetMaxDecompressedSize set the max size in bytes of a decompressed request body,
+// a non-positive n disables the limit
+func (s *Server) SetMaxDecompressedSize(n int64) {
+	s.maxDecompressedSize = n
+}
+
+// SetMaxBodyBytes set the server wide max size in bytes of request body,
+// a non-positive n disables the limit
+func (s *Server) SetMaxBodyBytes(n int64) {
+	s.maxBodyBytes = n
+}
+
+// SetMaxMultipartMemory set the max bytes of multipart form stored in memory,
+// the remainder is stored on disk in temporary files
+func (s *Server) SetMaxMultipartMemory(n int64) {
+	s.maxMultipartMemory = n
+}
+
+// routeKey identify a route by method and pattern
+type routeKey struct {
+	method string
+	path   string
+}
+
+// RouteMaxBodyBytes set the max size in bytes of request body of route with method and
+// pattern path, which overrides the server wide setting. The limit is resolved as soon as
+// request is routed, so that it also applies to server's filters reading the body.
+// A non-positive n disables the limit of the route.
+func (s *Server) RouteMaxBodyBytes(method, path string, n int64) {
+	if s.routeBodyLimits == nil {
+		s.routeBodyLimits = make(map[routeKey]int64)
+	}
+	s.routeBodyLimits[routeKey{method, path}] = n
+}
+
+// MaxBodyBytes wrap handler with a max request body size, which overrides the server wide
+// and route settings once handler is called. A body already read by filters is not limited
+// again, see Server.RouteMaxBodyBytes.
+func MaxBodyBytes(n int64, handler HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		c.maxBodyBytes = n
+		if n > 0 && c.Req.ContentLength > n {
+			c.bodyLimitErr = ErrBodyTooLarge
+			c.bodyError(ErrBodyTooLarge)
+			return
+		}
+		handler(c)
+	}
+}
+
+// prepareBody limit request's body size and wrap it with decoders of its
+// Content-Encoding, it runs only once before any Bind* or form parsing
+func (c *Context) prepareBody() error {
+	if c.bodyPrepared {
+		return c.bodyErr
+	}
+	c.bodyPrepared = true
+	if err := c.limitBody(); err != nil {
+		c.bodyErr = c.bodyError(err)
+		return c.bodyErr
+	}
+	c.bodyErr = c.bodyError(c.decompressBody())
+	return c.bodyErr
+}
+
+// limitBody enforce max body bytes on request's body via http.MaxBytesReader,
+// a body which declares a larger Content-Length is rejected without reading
+func (c *Context) limitBody() error {
+	max := c.maxBodyBytes
+	if max <= 0 || c.Req.Body == nil || c.Req.Body == http.NoBody {
+		return nil
+	}
+	if c.Req.ContentLength > max {
+		c.bodyLimitErr = ErrBodyTooLarge
+		return ErrBodyTooLarge
+	}
+	c.Req.Body = &maxBytesBody{
+		ReadCloser: http.MaxBytesReader(c.rw, c.Req.Body, max),
+		n:          max,
+	}
+	return nil
+}
+
+// bodyError translate err caused by an exceeded body limit into the limit's error,
+// and respond with 413 if nothing has been written yet
+func (c *Context) bodyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	limitErr := c.bodyLimitErr
+	if limitErr == nil {
+		limitErr = exceededLimit(c.Req.Body)
+	}
+	if limitErr == nil {
+		return err
+	}
+	if !c.rw.written {
+		http.Error(c.rw, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+	}
+	return limitErr
+}
+
+// exceededLimit return the error of a limit exceeded by reading body, if any. Limits are
+// recorded on body instead of Context, which a late reader may outlive under Timeout.
+func exceededLimit(body io.Reader) error {
+	switch b := body.(type) {
+	case *maxBytesBody:
+		return b.err
+	case *decompressedBody:
+		if l, ok := b.Reader.(*limitedReader); ok && l.n < 0 {
+			return l.err
+		}
+		return exceededLimit(b.compressed)
+	}
+	return nil
+}
+
+// decompressBody replace request's body with a transparently decompressed reader
+func (c *Context) decompressBody() error {
+	encoding := c.Req.Header.Get(contentEncoding)
+	if encoding == "" || c.Req.Body == nil || c.Req.Body == http.NoBody {
+		return nil
+	}
+
+	body := &decompressedBody{Reader: c.Req.Body, compressed: c.Req.Body, closers: []io.Closer{c.Req.Body}}
+	encodings := strings.Split(encoding, ",")
+	// encodings are listed in the order they were applied, decode in reverse
+	for i := len(encodings) - 1; i >= 0; i-- {
+		var (
+			r   io.ReadCloser
+			err error
+		)
+		switch strings.ToLower(strings.TrimSpace(encodings[i])) {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(body.Reader)
+		case "deflate":
+			r, err = newDeflateReader(body.Reader)
+		default:
+			return ErrUnsupportedContentEncoding
+		}
+		if err != nil {
+			return err
+		}
+		body.Reader = r
+		body.closers = append(body.closers, r)
+	}
+
+	if max := c.server.maxDecompressedSize; max > 0 {
+		body.Reader = &limitedReader{
+			r:   body.Reader,
+			n:   max,
+			err: ErrDecompressedBodyTooLarge,
+		}
+	}
+
+	c.Req.Body = body
+	c.Req.Header.Del(contentEncoding)
+	c.Req.ContentLength = -1
+	return nil
+}
+
+// newDeflateReader accept both zlib wrapped and raw deflate streams,
+// since clients disagree on what Content-Encoding: deflate means
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// decompressedBody close all decoders and the original body on Close
+type decompressedBody struct {
+	io.Reader
+	compressed io.Reader
+	closers    []io.Closer
+}
+
+func (b *decompressedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if e := b.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// maxBytesBody record ErrBodyTooLarge into err once http.MaxBytesReader hits its limit
+type maxBytesBody struct {
+	io.ReadCloser
+	n    int64
+	read int64
+	err  error
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.n {
+		b.err = ErrBodyTooLarge
+		err = ErrBodyTooLarge
+	}
+	return n, err
+}
+
+// limitedReader read at most n bytes from r, and return err once more data is available,
+// n is negative then
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		return 0, l.err
+	}
+	// read one byte more than allowed to tell an exact fit from an overflow
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.n {
+		n = int(l.n)
+		l.n = -1
+		return n, l.err
+	}
+	l.n -= int64(n)
+	return n, err
+}
@@ -0,0 +1,245 @@
+package zen
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func compress(t *testing.T, encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	default:
+		return data
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestContext_BindJSON_decompress(t *testing.T) {
+	payload := []byte(`{"name":"zen"}`)
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		max      int64
+		wantErr  error
+		wantName string
+	}{
+		{"identity", "", payload, defaultMaxDecompressedSize, nil, "zen"},
+		{"gzip", "gzip", compress(t, "gzip", payload), defaultMaxDecompressedSize, nil, "zen"},
+		{"zlib deflate", "deflate", compress(t, "zlib", payload), defaultMaxDecompressedSize, nil, "zen"},
+		{"raw deflate", "deflate", compress(t, "flate", payload), defaultMaxDecompressedSize, nil, "zen"},
+		{"exact limit", "gzip", compress(t, "gzip", payload), int64(len(payload)), nil, "zen"},
+		{"too large", "gzip", compress(t, "gzip", payload), 4, ErrDecompressedBodyTooLarge, ""},
+		{"unsupported", "br", payload, defaultMaxDecompressedSize, ErrUnsupportedContentEncoding, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.SetMaxDecompressedSize(tt.max)
+			req := httptest.NewRequest(POST, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set(contentEncoding, tt.encoding)
+			}
+			c := s.getContext(httptest.NewRecorder(), req)
+			defer s.putBackContext(c)
+
+			var input struct {
+				Name string `json:"name"`
+			}
+			err := c.BindJSON(&input)
+			if err != tt.wantErr {
+				t.Fatalf("Context.BindJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if input.Name != tt.wantName {
+				t.Errorf("Context.BindJSON() name = %q, want %q", input.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestContext_Form_decompress(t *testing.T) {
+	s := New()
+	req := httptest.NewRequest(POST, "/", bytes.NewReader(compress(t, "gzip", []byte("name=zen"))))
+	req.Header.Set(contentType, "application/x-www-form-urlencoded")
+	req.Header.Set(contentEncoding, "gzip")
+	c := s.getContext(httptest.NewRecorder(), req)
+	defer s.putBackContext(c)
+
+	if got := c.Form("name"); got != "zen" {
+		t.Errorf("Context.Form() = %q, want %q", got, "zen")
+	}
+	if c.Req.Header.Get(contentEncoding) != "" {
+		t.Errorf("Content-Encoding should be removed after decompression")
+	}
+}
+
+func TestContext_BindJSON_maxBodyBytes(t *testing.T) {
+	payload := []byte(`{"name":"zen"}`)
+	tests := []struct {
+		name          string
+		max           int64
+		contentLength int64
+		wantErr       error
+		wantCode      int
+	}{
+		{"unlimited", 0, int64(len(payload)), nil, 200},
+		{"fit", int64(len(payload)), int64(len(payload)), nil, 200},
+		{"content length", 4, int64(len(payload)), ErrBodyTooLarge, 413},
+		{"chunked", 4, -1, ErrBodyTooLarge, 413},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.SetMaxBodyBytes(tt.max)
+			req := httptest.NewRequest(POST, "/", bytes.NewReader(payload))
+			req.ContentLength = tt.contentLength
+			rw := httptest.NewRecorder()
+			c := s.getContext(rw, req)
+			defer s.putBackContext(c)
+
+			var input struct {
+				Name string `json:"name"`
+			}
+			if err := c.BindJSON(&input); err != tt.wantErr {
+				t.Fatalf("Context.BindJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rw.Code != tt.wantCode {
+				t.Errorf("Context.BindJSON() code = %d, want %d", rw.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	s := New()
+	s.SetMaxBodyBytes(4)
+	s.Route(POST, "/upload", MaxBodyBytes(1<<10, func(c *Context) {
+		if err := c.BindJSON(&struct{}{}); err != nil {
+			t.Errorf("BindJSON() error = %v", err)
+		}
+		c.WriteStatus(http.StatusNoContent)
+	}))
+	s.Route(POST, "/small", MaxBodyBytes(2, func(c *Context) {
+		t.Errorf("handler should not be called")
+	}))
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/upload", http.StatusNoContent},
+		{"/small", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, httptest.NewRequest(POST, tt.path, bytes.NewReader([]byte(`{"name":"zen"}`))))
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestServer_RouteMaxBodyBytes(t *testing.T) {
+	s := New()
+	s.SetMaxBodyBytes(8)
+	s.Filter(func(c *Context) {
+		c.Form("name")
+	})
+	var got string
+	s.Route(POST, "/", func(c *Context) {
+		got = c.Form("name")
+		c.WriteStatus(http.StatusNoContent)
+	})
+	s.RouteMaxBodyBytes(POST, "/", 1<<10)
+	// registered before its route, and applies to params routes
+	s.RouteMaxBodyBytes(POST, "/users/:id", 1<<10)
+	s.Route(POST, "/users/:id", func(c *Context) {
+		c.Form("name")
+		c.WriteStatus(http.StatusNoContent)
+	})
+	// handler wrapped by MaxBodyBytes is too late for filters
+	s.Route(POST, "/wrapped", MaxBodyBytes(1<<10, func(c *Context) {
+		t.Errorf("handler should not be called")
+	}))
+	s.Route(POST, "/default", func(c *Context) {
+		t.Errorf("handler should not be called")
+	})
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/", http.StatusNoContent},
+		{"/users/42", http.StatusNoContent},
+		{"/default", http.StatusRequestEntityTooLarge},
+		{"/wrapped", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(POST, tt.path, strings.NewReader("name=zen&age=300"))
+			req.Header.Set(contentType, applicationForm)
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+		})
+	}
+	if got != "zen" {
+		t.Errorf("Form() = %q, want zen", got)
+	}
+}
+
+func TestMaxBodyBytes_LateReader(t *testing.T) {
+	s := New()
+	s.SetMaxBodyBytes(8)
+	// prepare body before Timeout copies the context
+	s.Filter(func(c *Context) {
+		c.Form("name")
+	})
+	next, lateRead := make(chan struct{}), make(chan struct{})
+	s.Route(POST, "/slow", Timeout(10*time.Millisecond, func(c *Context) {
+		<-c.Req.Context().Done()
+		<-next
+		ioutil.ReadAll(c.Req.Body)
+		close(lateRead)
+	}))
+	var limitErr, bindErr error
+	s.Route(POST, "/next", func(c *Context) {
+		close(next)
+		<-lateRead
+		bindErr = c.BindJSON(&struct{}{})
+		limitErr = c.bodyLimitErr
+	})
+
+	req := httptest.NewRequest(POST, "/slow", strings.NewReader(`{"name":"too large"}`))
+	req.ContentLength = -1
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(POST, "/next", strings.NewReader(`{}`)))
+
+	if limitErr != nil || bindErr != nil {
+		t.Errorf("next request sees body error %v, %v of a late reader", limitErr, bindErr)
+	}
+}
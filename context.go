@@ -1,21 +1,31 @@
 package zen
 
 import (
-	"encoding/asn1"
+	"encoding"
 	"encoding/json"
 	"encoding/xml"
-	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"reflect"
-	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
 const (
-	inputTagName = "form"
-	validTagName = "valid"
-	validMsgName = "msg"
+	inputTagName  = "form"
+	paramTagName  = "param"
+	queryTagName  = "query"
+	headerTagName = "header"
+	layoutTagName = "layout"
+)
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
 //commonly used mime-types
@@ -31,10 +41,31 @@ const (
 type (
 	// Context warps request and response writer
 	Context struct {
-		Req    *http.Request
-		rw     *responseWriter
-		params Params
-		parsed bool
+		Req          *http.Request
+		rw           *responseWriter
+		params       Params
+		parsed       bool
+		server       *Server
+		bodyPrepared bool
+		bodyErr      error
+		bodyLimitErr error
+		maxBodyBytes int64
+		principal    interface{}
+		jwtClaims    *JWTClaims
+		sessionName  string
+		sessionStore SessionStore
+		session      *Session
+		csrfToken    string
+		cspNonce     string
+		locale       string
+		tempFiles    []string
+		query        url.Values
+		eventStream  *EventStream
+
+		proxyResolved bool
+		clientIP      string
+		scheme        string
+		host          string
 	}
 )
 
@@ -42,27 +73,55 @@ func (s *Server) getContext(rw http.ResponseWriter, req *http.Request) *Context
 	c := s.contextPool.Get().(*Context)
 	c.Req = req
 	c.rw.writer = rw
+	c.server = s
+	c.maxBodyBytes = s.maxBodyBytes
 	return c
 }
 
 func (s *Server) putBackContext(c *Context) {
 	c.params = c.params[0:0]
 	c.parsed = false
+	c.bodyPrepared = false
+	c.bodyErr = nil
+	c.bodyLimitErr = nil
+	c.principal = nil
+	c.jwtClaims = nil
+	c.sessionName = ""
+	c.sessionStore = nil
+	c.session = nil
+	c.csrfToken = ""
+	c.cspNonce = ""
+	c.locale = ""
+	c.query = nil
+	if c.eventStream != nil {
+		c.eventStream.Close()
+		c.eventStream = nil
+	}
+	c.proxyResolved = false
+	c.removeUploads()
 	c.Req = nil
 	c.rw.writer = nil
+	c.rw.written = false
 
 	s.contextPool.Put(c)
 }
 
 // parseInput will parse request's form and
 func (c *Context) parseInput() error {
+	if err := c.prepareBody(); err != nil {
+		c.parsed = true
+		return err
+	}
 	err1 := c.Req.ParseForm()
-	err2 := c.Req.ParseMultipartForm(32 << 10)
+	err2 := c.Req.ParseMultipartForm(c.server.maxMultipartMemory)
 	c.parsed = true
 	if err1 != nil {
-		return err1
+		return c.bodyError(err1)
+	}
+	if err2 == http.ErrNotMultipart {
+		return nil
 	}
-	return err2
+	return c.bodyError(err2)
 }
 
 // Form return request form value with given key
@@ -75,12 +134,7 @@ func (c *Context) Form(key string) string {
 
 // Param return url param with given key
 func (c *Context) Param(key string) string {
-	for _, p := range c.params {
-		if p.key == key {
-			return p.value
-		}
-	}
-	return ""
+	return c.params.Get(key)
 }
 
 // ParseValidateForm will parse request's form and map into a interface{} value
@@ -88,53 +142,248 @@ func (c *Context) ParseValidateForm(input interface{}) error {
 	return c.parseValidateForm(input)
 }
 
-// BindJSON will parse request's json body and map into a interface{} value
+// BindJSON will parse request's json body and map into a interface{} value,
+// then validate it the same way as ParseValidateForm
 func (c *Context) BindJSON(input interface{}) error {
-	if err := json.NewDecoder(c.Req.Body).Decode(input); err != nil {
+	if err := c.bindJSON(input); err != nil {
 		return err
 	}
-	return nil
+	return c.validate(input)
 }
 
-// BindXML will parse request's xml body and map into a interface{} value
+// BindXML will parse request's xml body and map into a interface{} value,
+// then validate it the same way as ParseValidateForm
 func (c *Context) BindXML(input interface{}) error {
-	if err := xml.NewDecoder(c.Req.Body).Decode(input); err != nil {
+	if err := c.bindXML(input); err != nil {
 		return err
 	}
+	return c.validate(input)
+}
+
+// bindJSON decode request's json body into input
+func (c *Context) bindJSON(input interface{}) error {
+	if err := c.prepareBody(); err != nil {
+		return err
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(input); err != nil {
+		return c.bodyError(err)
+	}
+	return nil
+}
+
+// bindXML decode request's xml body into input
+func (c *Context) bindXML(input interface{}) error {
+	if err := c.prepareBody(); err != nil {
+		return err
+	}
+	if err := xml.NewDecoder(c.Req.Body).Decode(input); err != nil {
+		return c.bodyError(err)
+	}
 	return nil
 }
 
 func (c *Context) parseValidateForm(input interface{}) error {
-	inputValue := reflect.ValueOf(input).Elem()
-	inputType := inputValue.Type()
-
-	for i := 0; i < inputValue.NumField(); i++ {
-		tag := inputType.Field(i).Tag
-		formName := tag.Get(inputTagName)
-		validate := tag.Get(validTagName)
-		validateMsg := tag.Get(validMsgName)
-		field := inputValue.Field(i)
-		formValue := c.Req.Form.Get(formName)
-
-		// scan form string value into field
-		if err := scan(field, formValue); err != nil {
+	return c.validateBound(input, c.bindForm(input))
+}
+
+// bindForm parse request's form and scan it into input's fields by form tag
+func (c *Context) bindForm(input interface{}) error {
+	if !c.parsed {
+		if err := c.parseInput(); err != nil {
 			return err
 		}
-		// validate form with regex
-		if err := valid(formValue, validate, validateMsg); err != nil {
-			return err
+	}
+
+	return bindValues(input, inputTagName, valuesGetter(c.Req.Form), valuesPrefixed(c.Req.Form))
+}
+
+// bindValues scan values returned by get into input's fields by tag,
+// fields whose key is absent are left untouched, nested structs are only
+// descended when prefixed reports any key with their prefix. Fields failing
+// to scan are reported together as ValidationErrors of rule type.
+func bindValues(input interface{}, tagName string, get func(key string) ([]string, bool), prefixed func(prefix string) bool) error {
+	inputValue := reflect.ValueOf(input)
+	if inputValue.Kind() != reflect.Ptr || inputValue.Elem().Kind() != reflect.Struct {
+		return ErrBindTarget
+	}
+	b := &valuesBinder{tagName: tagName, get: get, prefixed: prefixed}
+	b.bindStruct(inputValue.Elem(), "", "")
+	if len(b.errs) > 0 {
+		return b.errs
+	}
+	return nil
+}
+
+// valuesBinder scan values into struct fields by tag, see bindValues
+type valuesBinder struct {
+	tagName  string
+	get      func(key string) ([]string, bool)
+	prefixed func(prefix string) bool
+	errs     ValidationErrors
+}
+
+// bindStruct scan values into fields of struct v, keys of nested structs are
+// prefixed with the key of their parent field, such as address.city.
+// Fields of embedded structs are bound as if they were v's own fields.
+func (b *valuesBinder) bindStruct(v reflect.Value, namePrefix, keyPrefix string) (bound bool) {
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		field, fieldValue := t.Field(i), v.Field(i)
+		key := field.Tag.Get(b.tagName)
+		nested := isNestedStruct(field.Type)
+		if key == "" && !(nested && field.Anonymous) {
+			continue
 		}
 
+		var ok bool
+		switch {
+		case nested && key == "":
+			ok = b.bindNested(fieldValue, namePrefix, keyPrefix)
+		case nested:
+			if b.prefixed(keyPrefix + key + ".") {
+				ok = b.bindNested(fieldValue, namePrefix+field.Name+".", keyPrefix+key+".")
+			}
+		default:
+			var values []string
+			if values, ok = b.get(keyPrefix + key); ok {
+				if err := scanValues(fieldValue, values, field.Tag.Get(layoutTagName)); err != nil {
+					b.errs = append(b.errs, scanError(field, namePrefix+field.Name, keyPrefix+key, values))
+				}
+			}
+		}
+		bound = bound || ok
+	}
+	return bound
+}
+
+// bindNested bind struct or pointer to struct v, pointer is only allocated
+// when any of its fields is bound
+func (b *valuesBinder) bindNested(v reflect.Value, namePrefix, keyPrefix string) bool {
+	if v.Kind() != reflect.Ptr {
+		return b.bindStruct(v, namePrefix, keyPrefix)
+	}
+	if !v.IsNil() {
+		return b.bindStruct(v.Elem(), namePrefix, keyPrefix)
+	}
+	elem := reflect.New(v.Type().Elem())
+	bound := b.bindStruct(elem.Elem(), namePrefix, keyPrefix)
+	if bound && v.CanSet() {
+		v.Set(elem)
+	}
+	return bound
+}
+
+// scanError return the error of field failing to scan values, its rule is type
+// with the field's type as param
+func scanError(field reflect.StructField, name, key string, values []string) *FieldError {
+	err := &FieldError{Field: name, Key: key, Rule: "type", Param: field.Type.String()}
+	if len(values) == 1 {
+		err.Value = values[0]
+	} else {
+		err.Value = values
+	}
+	err.Message, err.messageKey = field.Tag.Get(validMsgName), field.Tag.Get(validMsgName)
+	if err.Message == "" {
+		err.Message = fmt.Sprintf("%s does not satisfy type=%s", name, err.Param)
+	}
+	return err
+}
+
+// isNestedStruct report whether t is a struct or pointer to struct bound field by field,
+// time.Time and text unmarshalers are scanned as a whole
+func isNestedStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// valuesGetter return a getter of values for bindValues
+func valuesGetter(values map[string][]string) func(key string) ([]string, bool) {
+	return func(key string) ([]string, bool) {
+		v := values[key]
+		return v, len(v) > 0
+	}
+}
+
+// valuesPrefixed return a reporter of whether values has any key with a prefix, for bindValues
+func valuesPrefixed(values map[string][]string) func(prefix string) bool {
+	return func(prefix string) bool {
+		for key := range values {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// scanValues scan values into v, slices are filled with every value, other kinds
+// take the first one
+func scanValues(v reflect.Value, values []string, layout string) error {
+	if !v.CanSet() {
+		return nil
+	}
+	if v.Kind() != reflect.Slice || reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return scan(v, values[0], layout)
+	}
+	if v.Type().Elem().Kind() == reflect.Uint8 {
+		v.SetBytes([]byte(values[0]))
+		return nil
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, s := range values {
+		if err := scan(slice.Index(i), s, layout); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
 	return nil
 }
 
-func scan(v reflect.Value, s string) error {
+// scan parse s into v, pointers are allocated, time.Time is parsed with layout
+// which defaults to RFC 3339, and text unmarshalers unmarshal s themselves
+func scan(v reflect.Value, s string, layout string) error {
 
 	if !v.CanSet() {
 		return nil
 	}
 
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := scan(elem.Elem(), s, layout); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
 	switch v.Kind() {
 	case reflect.String:
 		v.SetString(s)
@@ -147,21 +396,21 @@ func scan(v reflect.Value, s string) error {
 		v.SetBool(b)
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		i, err := strconv.ParseInt(s, 10, 64)
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
 		if err != nil {
 			return err
 		}
 		v.SetInt(i)
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		x, err := strconv.ParseUint(s, 10, 64)
+		x, err := strconv.ParseUint(s, 10, v.Type().Bits())
 		if err != nil {
 			return err
 		}
 		v.SetUint(x)
 
 	case reflect.Float32, reflect.Float64:
-		f, err := strconv.ParseFloat(s, 64)
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
 		if err != nil {
 			return err
 		}
@@ -171,59 +420,19 @@ func scan(v reflect.Value, s string) error {
 	return nil
 }
 
-func valid(s string, validate, msg string) error {
-	if validate == "" {
-		return nil
-	}
-	rxp, err := regexp.Compile(validate)
-	if err != nil {
-		return err
-	}
-
-	if !rxp.MatchString(s) {
-		return errors.New(msg)
-	}
-
-	return nil
-}
-
 // JSON : write json data to http response writer, with status code 200
-func (c *Context) JSON(i interface{}) (err error) {
-	// write http status code
-	c.WriteHeader(contentType, applicationJSON)
-
-	// Encode json data to rw
-	err = json.NewEncoder(c.rw).Encode(i)
-
-	//return
-	return
+func (c *Context) JSON(i interface{}) error {
+	return c.render(RenderJSON, i)
 }
 
 // XML : write xml data to http response writer, with status code 200
-func (c *Context) XML(i interface{}) (err error) {
-	// write http status code
-	c.WriteHeader(contentType, applicationXML)
-
-	// Encode xml data to rw
-	err = xml.NewEncoder(c.rw).Encode(i)
-
-	//return
-	return
+func (c *Context) XML(i interface{}) error {
+	return c.render(RenderXML, i)
 }
 
 // ASN1 : write asn1 data to http response writer, with status code 200
-func (c *Context) ASN1(i interface{}) (err error) {
-	// write http status code
-	c.WriteHeader(contentType, applicationASN1)
-
-	// Encode asn1 data to rw
-	bts, err := asn1.Marshal(i)
-	if err != nil {
-		return
-	}
-	//return
-	_, err = c.rw.Write(bts)
-	return
+func (c *Context) ASN1(i interface{}) error {
+	return c.render(RenderASN1, i)
 }
 
 // WriteStatus set response's status code
@@ -0,0 +1,13 @@
+package zen
+
+import "net/http"
+
+// Cookie return request's cookie with given name, or http.ErrNoCookie
+func (c *Context) Cookie(name string) (*http.Cookie, error) {
+	return c.Req.Cookie(name)
+}
+
+// SetCookie add a Set-Cookie header into response, it must be called before writing body
+func (c *Context) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(c.rw, cookie)
+}
@@ -0,0 +1,182 @@
+package zen
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+)
+
+const (
+	csrfTokenBytes = 32
+
+	defaultCSRFCookieName = "_csrf"
+	defaultCSRFFieldName  = "_csrf"
+	defaultCSRFHeaderName = "X-CSRF-Token"
+	csrfSessionKey        = "_csrf"
+)
+
+// CSRFConfig configure CSRF middleware
+type CSRFConfig struct {
+	// UseSession keeps the secret in session, the synchronizer token pattern,
+	// instead of a cookie, the double submit pattern. Sessions middleware must run first.
+	UseSession bool
+	// CookieName of the secret cookie, defaults to _csrf
+	CookieName string
+	// Cookie configures the secret cookie, HTTPOnly and SameSite default to true and Lax
+	Cookie SessionOptions
+	// FieldName of the form field holding token, defaults to _csrf
+	FieldName string
+	// HeaderName of the header holding token, defaults to X-CSRF-Token
+	HeaderName string
+	// TrustedOrigins are hosts, besides request's host, allowed in Origin and Referer
+	TrustedOrigins []string
+	// ErrorHandler is called when validation fails, defaults to 403
+	ErrorHandler HandlerFunc
+}
+
+// CSRF return a middleware which issue a token for every request, see Context.CSRFToken,
+// and validate it from form field or header on unsafe methods along with Origin and Referer
+func CSRF(config CSRFConfig) HandlerFunc {
+	if config.CookieName == "" {
+		config.CookieName = defaultCSRFCookieName
+	}
+	if config.FieldName == "" {
+		config.FieldName = defaultCSRFFieldName
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = defaultCSRFHeaderName
+	}
+	if config.Cookie.Path == "" {
+		config.Cookie.Path = "/"
+	}
+	if config.Cookie.SameSite == 0 {
+		config.Cookie.SameSite = http.SameSiteLaxMode
+	}
+	config.Cookie.HTTPOnly = true
+
+	return func(c *Context) {
+		secret, err := config.secret(c)
+		if err != nil {
+			config.fail(c)
+			return
+		}
+		c.csrfToken = maskCSRFToken(secret)
+
+		switch c.Req.Method {
+		case GET, HEAD, OPTIONS, TRACE:
+			return
+		}
+
+		if !config.sameOrigin(c) {
+			config.fail(c)
+			return
+		}
+
+		token := c.Req.Header.Get(config.HeaderName)
+		if token == "" {
+			token = c.Form(config.FieldName)
+		}
+		if !validCSRFToken(token, secret) {
+			config.fail(c)
+		}
+	}
+}
+
+// CSRFToken return the masked token of request issued by CSRF middleware,
+// it differs on every request to mitigate BREACH
+func (c *Context) CSRFToken() string {
+	return c.csrfToken
+}
+
+// secret load request's secret, a new one is issued if it is absent
+func (config *CSRFConfig) secret(c *Context) ([]byte, error) {
+	if config.UseSession {
+		session, err := c.Session()
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := session.Values[csrfSessionKey].(string); ok {
+			if secret, err := base64.RawURLEncoding.DecodeString(s); err == nil && len(secret) == csrfTokenBytes {
+				return secret, nil
+			}
+		}
+		encoded := randomToken(csrfTokenBytes)
+		session.Values[csrfSessionKey] = encoded
+		if err := c.SaveSession(); err != nil {
+			return nil, err
+		}
+		return base64.RawURLEncoding.DecodeString(encoded)
+	}
+
+	if cookie, err := c.Cookie(config.CookieName); err == nil {
+		if secret, err := base64.RawURLEncoding.DecodeString(cookie.Value); err == nil && len(secret) == csrfTokenBytes {
+			return secret, nil
+		}
+	}
+	encoded := randomToken(csrfTokenBytes)
+	c.SetCookie(config.Cookie.cookie(&Session{Name: config.CookieName}, encoded))
+	return base64.RawURLEncoding.DecodeString(encoded)
+}
+
+// sameOrigin check Origin, or Referer of https requests without Origin, against the host requested by client
+func (config *CSRFConfig) sameOrigin(c *Context) bool {
+	if origin := c.Req.Header.Get("Origin"); origin != "" {
+		return config.trustedURL(c, origin)
+	}
+	if c.Scheme() == "https" {
+		referer := c.Req.Referer()
+		return referer != "" && config.trustedURL(c, referer)
+	}
+	return true
+}
+
+func (config *CSRFConfig) trustedURL(c *Context, rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Host == c.Host() {
+		return true
+	}
+	for _, o := range config.TrustedOrigins {
+		if u.Host == o {
+			return true
+		}
+	}
+	return false
+}
+
+func (config *CSRFConfig) fail(c *Context) {
+	if config.ErrorHandler != nil {
+		config.ErrorHandler(c)
+		if c.rw.written {
+			return
+		}
+	}
+	http.Error(c.rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+}
+
+// maskCSRFToken return base64(mask | mask^secret) with a random mask
+func maskCSRFToken(secret []byte) string {
+	mask, _ := base64.RawURLEncoding.DecodeString(randomToken(len(secret)))
+	masked := make([]byte, 2*len(secret))
+	copy(masked, mask)
+	for i := range secret {
+		masked[len(secret)+i] = mask[i] ^ secret[i]
+	}
+	return base64.RawURLEncoding.EncodeToString(masked)
+}
+
+// validCSRFToken unmask token and compare it with secret in constant time
+func validCSRFToken(token string, secret []byte) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(masked) != 2*len(secret) {
+		return false
+	}
+	unmasked := make([]byte, len(secret))
+	for i := range unmasked {
+		unmasked[i] = masked[i] ^ masked[len(secret)+i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, secret) == 1
+}
@@ -0,0 +1,93 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCSRF(t *testing.T) {
+	tests := []struct {
+		name   string
+		config CSRFConfig
+	}{
+		{"double submit", CSRFConfig{TrustedOrigins: []string{"trusted.com"}}},
+		{"synchronizer", CSRFConfig{UseSession: true, TrustedOrigins: []string{"trusted.com"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			if tt.config.UseSession {
+				s.Filter(Sessions("session", NewMemorySessionStore([]byte("key"))))
+			}
+			s.Filter(CSRF(tt.config))
+			s.Get("/form", func(c *Context) {
+				c.RawStr(c.CSRFToken())
+			})
+			s.Route(POST, "/submit", func(c *Context) {
+				c.RawStr("ok")
+			})
+
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, httptest.NewRequest(GET, "/form", nil))
+			token := rw.Body.String()
+			cookies := (&http.Response{Header: rw.Header()}).Cookies()
+
+			cases := []struct {
+				name     string
+				field    string
+				header   string
+				origin   string
+				cookies  bool
+				wantCode int
+			}{
+				{"form field", token, "", "", true, http.StatusOK},
+				{"header", "", token, "", true, http.StatusOK},
+				{"trusted origin", token, "", "http://trusted.com", true, http.StatusOK},
+				{"same origin", token, "", "http://example.com", true, http.StatusOK},
+				{"missing token", "", "", "", true, http.StatusForbidden},
+				{"invalid token", "", "invalid", "", true, http.StatusForbidden},
+				{"cross origin", token, "", "http://evil.com", true, http.StatusForbidden},
+				{"missing secret", token, "", "", false, http.StatusForbidden},
+			}
+			for _, cc := range cases {
+				t.Run(cc.name, func(t *testing.T) {
+					form := url.Values{}
+					if cc.field != "" {
+						form.Set(defaultCSRFFieldName, cc.field)
+					}
+					req := httptest.NewRequest(POST, "/submit", strings.NewReader(form.Encode()))
+					req.Header.Set(contentType, "application/x-www-form-urlencoded")
+					if cc.header != "" {
+						req.Header.Set(defaultCSRFHeaderName, cc.header)
+					}
+					if cc.origin != "" {
+						req.Header.Set("Origin", cc.origin)
+					}
+					if cc.cookies {
+						for _, cookie := range cookies {
+							req.AddCookie(cookie)
+						}
+					}
+					rw := httptest.NewRecorder()
+					s.ServeHTTP(rw, req)
+					if rw.Code != cc.wantCode {
+						t.Errorf("code = %d, want %d", rw.Code, cc.wantCode)
+					}
+				})
+			}
+		})
+	}
+}
+
+func Test_validCSRFToken(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	if a, b := maskCSRFToken(secret), maskCSRFToken(secret); a == b {
+		t.Errorf("maskCSRFToken() returned the same token twice")
+	}
+	if !validCSRFToken(maskCSRFToken(secret), secret) {
+		t.Errorf("validCSRFToken() = false for a masked secret")
+	}
+}
@@ -0,0 +1,64 @@
+package zen
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// IPFilter allow or deny requests by client's ip, it can be used globally
+// with Server.Filter(f.Filter) or on a route with Chain(f.Filter, handler)
+type IPFilter struct {
+	mu    sync.RWMutex
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// NewIPFilter create an IPFilter with allow and deny lists of CIDRs or IPs, see IPFilter.Update
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	f := &IPFilter{}
+	if err := f.Update(allow, deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update replace allow and deny lists at runtime. Deny list wins over allow list,
+// and every ip which is not denied is allowed if allow list is empty.
+func (f *IPFilter) Update(allow, deny []string) error {
+	allowNets, err := parseCIDRs(allow)
+	if err != nil {
+		return err
+	}
+	denyNets, err := parseCIDRs(deny)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.allow, f.deny = allowNets, denyNets
+	f.mu.Unlock()
+	return nil
+}
+
+// Allowed report whether ip is allowed
+func (f *IPFilter) Allowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if containsIP(f.deny, parsed) {
+		return false
+	}
+	return len(f.allow) == 0 || containsIP(f.allow, parsed)
+}
+
+// Filter respond 403 if client's ip is not allowed, see Context.ClientIP
+func (f *IPFilter) Filter(c *Context) {
+	if !f.Allowed(c.ClientIP()) {
+		http.Error(c.rw, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	}
+}
@@ -0,0 +1,82 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilter_Allowed(t *testing.T) {
+	f, err := NewIPFilter([]string{"192.0.2.0/24", "2001:db8::/32"}, []string{"192.0.2.13"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.0.2.1", true},
+		{"192.0.2.13", false},
+		{"198.51.100.1", false},
+		{"2001:db8::1", true},
+		{"2001:db9::1", false},
+		{"invalid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := f.Allowed(tt.ip); got != tt.want {
+				t.Errorf("Allowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPFilter_Filter(t *testing.T) {
+	f, _ := NewIPFilter([]string{"192.0.2.0/24"}, nil)
+	s := New()
+	s.SetTrustedProxies("10.0.0.1")
+	s.Get("/admin", Chain(f.Filter, func(c *Context) {
+		c.RawStr("ok")
+	}))
+	s.Get("/public", func(c *Context) {
+		c.RawStr("ok")
+	})
+
+	serve := func(path, remoteAddr, xff string) int {
+		req := httptest.NewRequest(GET, path, nil)
+		req.RemoteAddr = remoteAddr
+		if xff != "" {
+			req.Header.Set(xForwardedFor, xff)
+		}
+		rw := httptest.NewRecorder()
+		s.ServeHTTP(rw, req)
+		return rw.Code
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		xff        string
+		wantCode   int
+	}{
+		{"allowed", "/admin", "192.0.2.1:1234", "", http.StatusOK},
+		{"denied", "/admin", "198.51.100.1:1234", "", http.StatusForbidden},
+		{"allowed behind proxy", "/admin", "10.0.0.1:1234", "192.0.2.1", http.StatusOK},
+		{"spoofed header", "/admin", "198.51.100.1:1234", "192.0.2.1", http.StatusForbidden},
+		{"other route", "/public", "198.51.100.1:1234", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serve(tt.path, tt.remoteAddr, tt.xff); got != tt.wantCode {
+				t.Errorf("code = %d, want %d", got, tt.wantCode)
+			}
+		})
+	}
+
+	// hot reload
+	f.Update([]string{"198.51.100.0/24"}, nil)
+	if got := serve("/admin", "198.51.100.1:1234", ""); got != http.StatusOK {
+		t.Errorf("code after Update() = %d, want %d", got, http.StatusOK)
+	}
+}
@@ -0,0 +1,478 @@
+package zen
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// supported jwt algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+
+	// defaultJWKSRefreshInterval is the min interval between two JWKS refreshes caused by unknown key ids
+	defaultJWKSRefreshInterval = time.Minute
+)
+
+var (
+	// ErrJWTMalformed is returned when a token can not be decoded
+	ErrJWTMalformed = errors.New("zen: malformed jwt")
+	// ErrJWTAlgorithm is returned when a token is signed by an algorithm which is not allowed
+	ErrJWTAlgorithm = errors.New("zen: jwt algorithm not allowed")
+	// ErrJWTKeyNotFound is returned when no key matches a token's key id
+	ErrJWTKeyNotFound = errors.New("zen: jwt key not found")
+	// ErrJWTSignature is returned when a token's signature is invalid
+	ErrJWTSignature = errors.New("zen: invalid jwt signature")
+	// ErrJWTExpired is returned when a token's exp is passed
+	ErrJWTExpired = errors.New("zen: jwt expired")
+	// ErrJWTNotYetValid is returned when a token's nbf is not reached
+	ErrJWTNotYetValid = errors.New("zen: jwt not valid yet")
+	// ErrJWTIssuer is returned when a token's iss is unexpected
+	ErrJWTIssuer = errors.New("zen: invalid jwt issuer")
+	// ErrJWTAudience is returned when a token's aud does not contain the expected audience
+	ErrJWTAudience = errors.New("zen: invalid jwt audience")
+)
+
+type (
+	// JWTClaims is the registered claims of a verified token
+	JWTClaims struct {
+		Issuer    string      `json:"iss,omitempty"`
+		Subject   string      `json:"sub,omitempty"`
+		Audience  JWTAudience `json:"aud,omitempty"`
+		ExpiresAt int64       `json:"exp,omitempty"`
+		NotBefore int64       `json:"nbf,omitempty"`
+		IssuedAt  int64       `json:"iat,omitempty"`
+		ID        string      `json:"jti,omitempty"`
+
+		payload []byte
+	}
+
+	// JWTAudience is the aud claim, which is either a string or an array of strings
+	JWTAudience []string
+
+	// JWTKeySource return the verification key of a token, which is a []byte for HS256,
+	// a *rsa.PublicKey for RS256 or an *ecdsa.PublicKey for ES256
+	JWTKeySource interface {
+		JWTKey(kid, alg string) (interface{}, error)
+	}
+
+	// JWTKeyFunc adapt a function into JWTKeySource
+	JWTKeyFunc func(kid, alg string) (interface{}, error)
+
+	// JWTConfig configure JWT verification
+	JWTConfig struct {
+		// Keys is required, see JWKS and JWTKeyFunc
+		Keys JWTKeySource
+		// Algorithms allowed, defaults to HS256, RS256 and ES256
+		Algorithms []string
+		// Issuer is compared with iss if not empty
+		Issuer string
+		// Audience must be contained in aud if not empty
+		Audience string
+		// Leeway tolerates clock skew when checking exp and nbf
+		Leeway time.Duration
+		// Realm is sent in WWW-Authenticate challenge
+		Realm string
+	}
+
+	// JWKS is a JSON Web Key Set, which is reloaded when a token refers to an unknown key id,
+	// so that keys can be rotated without restart
+	JWKS struct {
+		load        func() ([]byte, error)
+		minInterval time.Duration
+
+		// refreshMu serialize refreshes, so that concurrent lookups of unknown
+		// key ids wait for a single load
+		refreshMu sync.Mutex
+		// attempted is the time of the last refresh, successful or not
+		attempted time.Time
+
+		mu   sync.RWMutex
+		keys map[string]interface{}
+	}
+
+	jsonWebKey struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+		K   string `json:"k"`
+	}
+
+	jwtHeader struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+)
+
+// JWTAuth return a middleware which verify the bearer token of requests,
+// and store the verified claims on Context, see Context.JWTClaims
+func JWTAuth(config JWTConfig) HandlerFunc {
+	assert(config.Keys != nil, "jwt keys cannot be nil")
+	challenge := "Bearer realm=" + strconv.Quote(config.Realm)
+
+	return func(c *Context) {
+		token, ok := c.bearerToken()
+		if !ok {
+			c.unauthorized(challenge)
+			return
+		}
+
+		claims, err := VerifyJWT(token, config)
+		if err != nil {
+			c.unauthorized(challenge + `, error="invalid_token", error_description=` + strconv.Quote(err.Error()))
+			return
+		}
+		c.jwtClaims = claims
+		c.SetPrincipal(claims)
+	}
+}
+
+// JWTClaims return claims verified by JWTAuth, or nil
+func (c *Context) JWTClaims() *JWTClaims {
+	return c.jwtClaims
+}
+
+// Unmarshal decode the whole claims set, including private claims, into v
+func (claims *JWTClaims) Unmarshal(v interface{}) error {
+	return json.Unmarshal(claims.payload, v)
+}
+
+// UnmarshalJSON implement json.Unmarshaler
+func (aud *JWTAudience) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*aud = JWTAudience{s}
+		return nil
+	}
+	var a []string
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	*aud = JWTAudience(a)
+	return nil
+}
+
+// Contains report whether aud contains audience
+func (aud JWTAudience) Contains(audience string) bool {
+	for _, a := range aud {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTKey implement JWTKeySource
+func (f JWTKeyFunc) JWTKey(kid, alg string) (interface{}, error) {
+	return f(kid, alg)
+}
+
+// VerifyJWT verify token's signature and registered claims with config
+func VerifyJWT(token string, config JWTConfig) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header jwtHeader
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if !jwtAlgorithmAllowed(header.Alg, config.Algorithms) {
+		return nil, ErrJWTAlgorithm
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	key, err := config.Keys.JWTKey(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyJWTSignature(header.Alg, key, parts[0]+"."+parts[1], signature) {
+		return nil, ErrJWTSignature
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+	claims := &JWTClaims{payload: payload}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, ErrJWTMalformed
+	}
+	if err := claims.validate(config, time.Now()); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validate check registered claims at now
+func (claims *JWTClaims) validate(config JWTConfig, now time.Time) error {
+	leeway := int64(config.Leeway / time.Second)
+	unix := now.Unix()
+	if claims.ExpiresAt != 0 && unix > claims.ExpiresAt+leeway {
+		return ErrJWTExpired
+	}
+	if claims.NotBefore != 0 && unix+leeway < claims.NotBefore {
+		return ErrJWTNotYetValid
+	}
+	if config.Issuer != "" && claims.Issuer != config.Issuer {
+		return ErrJWTIssuer
+	}
+	if config.Audience != "" && !claims.Audience.Contains(config.Audience) {
+		return ErrJWTAudience
+	}
+	return nil
+}
+
+func decodeJWTSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrJWTMalformed
+	}
+	return nil
+}
+
+func jwtAlgorithmAllowed(alg string, allowed []string) bool {
+	if len(allowed) == 0 {
+		allowed = []string{HS256, RS256, ES256}
+	}
+	for _, a := range allowed {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyJWTSignature verify signature of signed with key, key's type must match alg
+func verifyJWTSignature(alg string, key interface{}, signed string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		return hmac.Equal(signature, mac.Sum(nil))
+
+	case RS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+
+	case ES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	}
+	return false
+}
+
+// -----------------------------------------------------------------------------
+// JWKS
+
+// NewJWKS create a JWKS whose document is returned by load
+func NewJWKS(load func() ([]byte, error)) (*JWKS, error) {
+	assert(load != nil, "jwks load cannot be nil")
+	set := &JWKS{load: load, minInterval: defaultJWKSRefreshInterval}
+	if err := set.Refresh(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// NewJWKSFile create a JWKS loaded from file at path
+func NewJWKSFile(path string) (*JWKS, error) {
+	return NewJWKS(func() ([]byte, error) {
+		return ioutil.ReadFile(path)
+	})
+}
+
+// NewJWKSURL create a JWKS fetched from url with client,
+// http.DefaultClient is used if client is nil
+func NewJWKSURL(url string, client *http.Client) (*JWKS, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return NewJWKS(func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, errors.New("zen: fetch jwks: " + resp.Status)
+		}
+		return ioutil.ReadAll(resp.Body)
+	})
+}
+
+// SetRefreshInterval set the min interval between two refreshes caused by unknown key ids
+func (set *JWKS) SetRefreshInterval(d time.Duration) {
+	set.mu.Lock()
+	set.minInterval = d
+	set.mu.Unlock()
+}
+
+// Refresh reload the key set, keys of the previous set are dropped,
+// and they are kept if reloading fails
+func (set *JWKS) Refresh() error {
+	set.refreshMu.Lock()
+	defer set.refreshMu.Unlock()
+	return set.refresh()
+}
+
+// refresh reload the key set, the caller must hold refreshMu
+func (set *JWKS) refresh() error {
+	// a failed load counts as a refresh too, so that tokens with unknown
+	// key ids can't make the set reload continuously while it fails
+	set.attempted = time.Now()
+
+	data, err := set.load()
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	set.mu.Lock()
+	set.keys = keys
+	set.mu.Unlock()
+	return nil
+}
+
+// JWTKey implement JWTKeySource, an unknown kid triggers a refresh at most once per refresh interval,
+// concurrent lookups share the same refresh
+func (set *JWKS) JWTKey(kid, alg string) (interface{}, error) {
+	if key, ok := set.key(kid); ok {
+		return key, nil
+	}
+
+	set.refreshMu.Lock()
+	defer set.refreshMu.Unlock()
+	// the set may have been refreshed while waiting
+	if key, ok := set.key(kid); ok {
+		return key, nil
+	}
+	set.mu.RLock()
+	stale := time.Since(set.attempted) >= set.minInterval
+	set.mu.RUnlock()
+	if !stale || set.refresh() != nil {
+		return nil, ErrJWTKeyNotFound
+	}
+	if key, ok := set.key(kid); ok {
+		return key, nil
+	}
+	return nil, ErrJWTKeyNotFound
+}
+
+// key return the key of kid in current set
+func (set *JWKS) key(kid string) (interface{}, bool) {
+	set.mu.RLock()
+	defer set.mu.RUnlock()
+	key, ok := set.keys[kid]
+	return key, ok
+}
+
+// parseJWKS parse a JWKS document into keys by kid
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var doc struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(doc.Keys))
+	for _, k := range doc.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey decode k into a verification key, unsupported key types are ignored
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, errors.New("zen: jwk " + k.Kid + " is not on curve P-256")
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, nil
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
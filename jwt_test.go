@@ -0,0 +1,214 @@
+package zen
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func encodeJWKInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func TestVerifyJWT(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys := JWTKeyFunc(func(kid, alg string) (interface{}, error) {
+		switch kid {
+		case "hs":
+			return secret, nil
+		case "rs":
+			return &rsaKey.PublicKey, nil
+		case "es":
+			return &ecKey.PublicKey, nil
+		}
+		return nil, ErrJWTKeyNotFound
+	})
+	config := JWTConfig{Keys: keys, Issuer: "zen", Audience: "api", Leeway: time.Second}
+	now := time.Now().Unix()
+	valid := map[string]interface{}{"iss": "zen", "aud": []string{"web", "api"}, "exp": now + 60, "sub": "user"}
+
+	tests := []struct {
+		name    string
+		token   string
+		config  JWTConfig
+		wantErr error
+	}{
+		{"HS256", signJWT(t, HS256, "hs", secret, valid), config, nil},
+		{"RS256", signJWT(t, RS256, "rs", rsaKey, valid), config, nil},
+		{"ES256", signJWT(t, ES256, "es", ecKey, valid), config, nil},
+		{"string audience", signJWT(t, HS256, "hs", secret, map[string]interface{}{"iss": "zen", "aud": "api"}), config, nil},
+		{"malformed", "a.b", config, ErrJWTMalformed},
+		{"wrong key", signJWT(t, HS256, "hs", []byte("other"), valid), config, ErrJWTSignature},
+		{"key confusion", signJWT(t, HS256, "rs", secret, valid), config, ErrJWTSignature},
+		{"unknown key", signJWT(t, HS256, "none", secret, valid), config, ErrJWTKeyNotFound},
+		{"algorithm", signJWT(t, HS256, "hs", secret, valid), JWTConfig{Keys: keys, Algorithms: []string{RS256}}, ErrJWTAlgorithm},
+		{"expired", signJWT(t, HS256, "hs", secret, map[string]interface{}{"iss": "zen", "aud": "api", "exp": now - 10}), config, ErrJWTExpired},
+		{"not before", signJWT(t, HS256, "hs", secret, map[string]interface{}{"iss": "zen", "aud": "api", "nbf": now + 10}), config, ErrJWTNotYetValid},
+		{"issuer", signJWT(t, HS256, "hs", secret, map[string]interface{}{"iss": "other", "aud": "api"}), config, ErrJWTIssuer},
+		{"audience", signJWT(t, HS256, "hs", secret, map[string]interface{}{"iss": "zen", "aud": "web"}), config, ErrJWTAudience},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := VerifyJWT(tt.token, tt.config)
+			if err != tt.wantErr {
+				t.Errorf("VerifyJWT() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJWKS_rotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	docs := []string{
+		`{"keys":[{"kty":"RSA","kid":"old","n":"` + encodeJWKInt(oldKey.N) + `","e":"AQAB"}]}`,
+		`{"keys":[{"kty":"EC","kid":"new","crv":"P-256","x":"` + encodeJWKInt(newKey.X) + `","y":"` + encodeJWKInt(newKey.Y) + `"}]}`,
+	}
+	current := 0
+	idp := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte(docs[current]))
+	}))
+	defer idp.Close()
+
+	set, err := NewJWKSURL(idp.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.SetRefreshInterval(0)
+
+	s := New()
+	s.Filter(JWTAuth(JWTConfig{Keys: set, Realm: "api"}))
+	s.Get("/", func(c *Context) {
+		var claims struct {
+			Role string `json:"role"`
+		}
+		c.JWTClaims().Unmarshal(&claims)
+		c.RawStr(c.JWTClaims().Subject + ":" + claims.Role)
+	})
+
+	tests := []struct {
+		name     string
+		token    string
+		rotate   bool
+		wantCode int
+		wantBody string
+	}{
+		{"old key", signJWT(t, RS256, "old", oldKey, map[string]interface{}{"sub": "a", "role": "admin"}), false, http.StatusOK, "a:admin"},
+		{"new key before rotation", signJWT(t, ES256, "new", newKey, map[string]interface{}{"sub": "b"}), false, http.StatusUnauthorized, ""},
+		{"new key after rotation", signJWT(t, ES256, "new", newKey, map[string]interface{}{"sub": "b"}), true, http.StatusOK, "b:"},
+		{"old key after rotation", signJWT(t, RS256, "old", oldKey, map[string]interface{}{"sub": "a"}), false, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.rotate {
+				current = 1
+			}
+			req := httptest.NewRequest(GET, "/", nil)
+			req.Header.Set(authorization, "Bearer "+tt.token)
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && rw.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rw.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestJWKS_refreshFailure(t *testing.T) {
+	var mu sync.Mutex
+	loads := 0
+	fail := false
+	set, err := NewJWKS(func() ([]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		if fail {
+			time.Sleep(10 * time.Millisecond)
+			return nil, errors.New("idp is down")
+		}
+		return []byte(`{"keys":[]}`), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.SetRefreshInterval(time.Hour)
+	mu.Lock()
+	fail = true
+	mu.Unlock()
+	// make the set stale
+	set.attempted = time.Time{}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := set.JWTKey("unknown", RS256)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != ErrJWTKeyNotFound {
+			t.Errorf("JWTKey() error = %v, want ErrJWTKeyNotFound", err)
+		}
+	}
+	if _, err := set.JWTKey("unknown", RS256); err != ErrJWTKeyNotFound {
+		t.Errorf("JWTKey() error = %v, want ErrJWTKeyNotFound", err)
+	}
+	// the initial load and a single failed refresh
+	if loads != 2 {
+		t.Errorf("loads = %d, want 2", loads)
+	}
+}
@@ -0,0 +1,189 @@
+package zen
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// loadShedDecrease is the factor applied to an adaptive limit when latency is above target
+	loadShedDecrease = 0.9
+	// loadShedEWMAWeight is the weight of a new latency sample
+	loadShedEWMAWeight = 0.1
+	// loadShedAdjustInterval is the min interval between two adaptive limit adjustments
+	loadShedAdjustInterval = 100 * time.Millisecond
+)
+
+type (
+	// LoadShedConfig configure a LoadShedder
+	LoadShedConfig struct {
+		// MaxInFlight is the max count of requests handled concurrently, required
+		MaxInFlight int
+		// MaxQueue is the max count of requests waiting for a slot, others are shed
+		MaxQueue int
+		// QueueTimeout is the max time a request waits in queue, zero means until request is canceled
+		QueueTimeout time.Duration
+		// RetryAfter is sent with shed responses, defaults to one second
+		RetryAfter time.Duration
+		// TargetLatency enables adaptive limiting when positive, the in-flight limit
+		// is lowered while observed latency stays above it and raised back otherwise
+		TargetLatency time.Duration
+	}
+
+	// LoadShedder cap in-flight requests, queue a bounded number of them
+	// and shed the rest with 503
+	LoadShedder struct {
+		config LoadShedConfig
+
+		mu       sync.Mutex
+		limit    int
+		inFlight int
+		waiters  []chan struct{}
+		latency  float64
+		adjusted time.Time
+	}
+)
+
+// NewLoadShedder create a LoadShedder with config
+func NewLoadShedder(config LoadShedConfig) *LoadShedder {
+	assert(config.MaxInFlight > 0, "max in-flight requests must be positive")
+	if config.RetryAfter <= 0 {
+		config.RetryAfter = time.Second
+	}
+	return &LoadShedder{config: config, limit: config.MaxInFlight}
+}
+
+// ShedLoad apply l to every request served by server, before routing
+func (s *Server) ShedLoad(l *LoadShedder) {
+	s.loadShedder = l
+}
+
+// Handler wrap handler with l, so that l only applies to a route
+func (l *LoadShedder) Handler(handler HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		if !l.acquire(c.Req.Context()) {
+			l.shed(c.rw)
+			return
+		}
+		start := time.Now()
+		defer l.release(start)
+		handler(c)
+	}
+}
+
+// InFlight return count of requests being handled
+func (l *LoadShedder) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// QueueDepth return count of requests waiting for a slot
+func (l *LoadShedder) QueueDepth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiters)
+}
+
+// Limit return current in-flight limit, which differs from MaxInFlight only when adaptive
+func (l *LoadShedder) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// acquire take a slot, waiting in queue if needed, return false if request should be shed
+func (l *LoadShedder) acquire(ctx context.Context) bool {
+	l.mu.Lock()
+	if l.inFlight < l.limit {
+		l.inFlight++
+		l.mu.Unlock()
+		return true
+	}
+	if len(l.waiters) >= l.config.MaxQueue {
+		l.mu.Unlock()
+		return false
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if l.config.QueueTimeout > 0 {
+		timer := time.NewTimer(l.config.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ready:
+		return true
+	case <-timeout:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, w := range l.waiters {
+		if w == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return false
+		}
+	}
+	// a slot was handed over while giving up, keep it
+	return true
+}
+
+// release give back the slot taken at start, handing it over to queued requests
+func (l *LoadShedder) release(start time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.TargetLatency > 0 {
+		l.adapt(time.Since(start))
+	}
+
+	l.inFlight--
+	for len(l.waiters) > 0 && l.inFlight < l.limit {
+		l.inFlight++
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
+
+// adapt update latency average and adjust limit, l.mu must be held
+func (l *LoadShedder) adapt(latency time.Duration) {
+	if l.latency == 0 {
+		l.latency = float64(latency)
+	} else {
+		l.latency += loadShedEWMAWeight * (float64(latency) - l.latency)
+	}
+
+	now := time.Now()
+	if now.Sub(l.adjusted) < loadShedAdjustInterval {
+		return
+	}
+	l.adjusted = now
+
+	if l.latency > float64(l.config.TargetLatency) {
+		if limit := int(float64(l.limit) * loadShedDecrease); limit < l.limit {
+			l.limit = limit
+		} else {
+			l.limit--
+		}
+		if l.limit < 1 {
+			l.limit = 1
+		}
+	} else if l.limit < l.config.MaxInFlight {
+		l.limit++
+	}
+}
+
+// shed respond 503 with Retry-After
+func (l *LoadShedder) shed(rw http.ResponseWriter) {
+	rw.Header().Set(retryAfter, strconv.Itoa(ceilSeconds(l.config.RetryAfter)))
+	http.Error(rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
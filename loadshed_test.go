@@ -0,0 +1,105 @@
+package zen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadShedder_acquire(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("shed", func(t *testing.T) {
+		l := NewLoadShedder(LoadShedConfig{MaxInFlight: 1})
+		if !l.acquire(ctx) {
+			t.Fatal("first acquire() = false, want true")
+		}
+		if l.acquire(ctx) {
+			t.Error("second acquire() = true, want false")
+		}
+	})
+
+	t.Run("queue timeout", func(t *testing.T) {
+		l := NewLoadShedder(LoadShedConfig{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond})
+		l.acquire(ctx)
+		if l.acquire(ctx) {
+			t.Error("queued acquire() = true, want false after timeout")
+		}
+		if got := l.QueueDepth(); got != 0 {
+			t.Errorf("QueueDepth() = %d, want 0", got)
+		}
+	})
+
+	t.Run("hand over", func(t *testing.T) {
+		l := NewLoadShedder(LoadShedConfig{MaxInFlight: 1, MaxQueue: 1})
+		l.acquire(ctx)
+		queued := make(chan bool)
+		go func() {
+			queued <- l.acquire(ctx)
+		}()
+		for l.QueueDepth() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+		if l.acquire(ctx) {
+			t.Error("acquire() with full queue = true, want false")
+		}
+		l.release(time.Now())
+		if !<-queued {
+			t.Error("queued acquire() = false, want true")
+		}
+		if got := l.InFlight(); got != 1 {
+			t.Errorf("InFlight() = %d, want 1", got)
+		}
+	})
+}
+
+func TestServer_ShedLoad(t *testing.T) {
+	l := NewLoadShedder(LoadShedConfig{MaxInFlight: 1, RetryAfter: 2 * time.Second})
+	s := New()
+	s.ShedLoad(l)
+	s.Get("/", func(c *Context) {
+		c.WriteStatus(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name      string
+		busy      bool
+		wantCode  int
+		wantRetry string
+	}{
+		{"idle", false, http.StatusNoContent, ""},
+		{"busy", true, http.StatusServiceUnavailable, "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.busy {
+				l.acquire(context.Background())
+				defer l.release(time.Now())
+			}
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, httptest.NewRequest(GET, "/", nil))
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got := rw.Header().Get(retryAfter); got != tt.wantRetry {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestLoadShedder_adapt(t *testing.T) {
+	l := NewLoadShedder(LoadShedConfig{MaxInFlight: 10, TargetLatency: time.Millisecond})
+	l.adapt(10 * time.Millisecond)
+	if got := l.Limit(); got != 9 {
+		t.Errorf("Limit() after slow request = %d, want 9", got)
+	}
+	l.adjusted = time.Time{}
+	l.latency = 0
+	l.adapt(0)
+	if got := l.Limit(); got != 10 {
+		t.Errorf("Limit() after fast request = %d, want 10", got)
+	}
+}
@@ -0,0 +1,198 @@
+package zen
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	acceptLanguage = "Accept-Language"
+
+	defaultLocale = "en"
+)
+
+// LocaleResolver return the locale of request, such as en or fr-CA, an empty
+// locale falls back to Accept-Language header
+type LocaleResolver func(c *Context) string
+
+// languageRange is a language range of Accept-Language header
+type languageRange struct {
+	tag string
+	q   float64
+}
+
+// RegisterMessages register messages of locale, which are merged into messages
+// registered before. Messages are text/template templates executed with the
+// FieldError, such as "{{.Field}} must be at least {{.Param}} characters".
+// A validation error is translated by the key in its field's msg tag, or by its
+// rule name when field has no msg tag.
+func (s *Server) RegisterMessages(locale string, messages map[string]string) error {
+	locale = strings.ToLower(locale)
+	catalog := s.messages[locale]
+	if catalog == nil {
+		catalog = make(map[string]*template.Template, len(messages))
+	}
+	for key, message := range messages {
+		tmpl, err := template.New(key).Option("missingkey=zero").Parse(message)
+		if err != nil {
+			return err
+		}
+		catalog[key] = tmpl
+	}
+	if s.messages == nil {
+		s.messages = make(map[string]map[string]*template.Template)
+	}
+	s.messages[locale] = catalog
+	return nil
+}
+
+// SetDefaultLocale set the locale used when client accepts none of registered locales, defaults to en
+func (s *Server) SetDefaultLocale(locale string) {
+	s.defaultLocale = strings.ToLower(locale)
+}
+
+// SetLocaleResolver set resolver of request's locale, such as from user's profile or url
+func (s *Server) SetLocaleResolver(resolver LocaleResolver) {
+	s.localeResolver = resolver
+}
+
+// Locale return the locale of request, which is set by SetLocale, returned by server's
+// locale resolver, or the registered locale which best matches Accept-Language header
+func (c *Context) Locale() string {
+	if c.locale != "" {
+		return c.locale
+	}
+	if r := c.server.localeResolver; r != nil {
+		c.locale = strings.ToLower(r(c))
+	}
+	if c.locale == "" {
+		c.locale = c.server.matchLocale(c.Req.Header.Get(acceptLanguage))
+	}
+	return c.locale
+}
+
+// SetLocale set the locale of request
+func (c *Context) SetLocale(locale string) {
+	c.locale = strings.ToLower(locale)
+}
+
+// validate input with server's rules, and translate messages of validation errors to request's locale
+func (c *Context) validate(input interface{}) error {
+	return c.validateBound(input, nil)
+}
+
+// validateBound validate input which is bound with err, fields failing to be bound are
+// reported together with fields failing their rules, other errors are returned as they are
+func (c *Context) validateBound(input interface{}, err error) error {
+	errs, ok := err.(ValidationErrors)
+	if err != nil && !ok {
+		return err
+	}
+	err = c.server.validate(input)
+	ruleErrs, ok := err.(ValidationErrors)
+	if err != nil && !ok {
+		return err
+	}
+	for _, e := range ruleErrs {
+		// the rules of a field failing to be bound see its zero value
+		if !errs.has(e.Field) {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	if len(c.server.messages) > 0 {
+		locale := c.Locale()
+		for _, e := range errs {
+			c.server.translate(locale, e)
+		}
+	}
+	return errs
+}
+
+// translate e's message to locale, e is untouched if no message is registered
+func (s *Server) translate(locale string, e *FieldError) {
+	key := e.messageKey
+	if key == "" {
+		key = e.Rule
+	}
+	tmpl, ok := s.message(locale, key)
+	if !ok {
+		return
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, e); err == nil {
+		e.Message = buf.String()
+	}
+}
+
+// message return the message of key in locale, falling back to locale's base language,
+// then to default locale
+func (s *Server) message(locale, key string) (*template.Template, bool) {
+	candidates := []string{locale}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, s.defaultLocale)
+
+	for _, l := range candidates {
+		if tmpl, ok := s.messages[l][key]; ok {
+			return tmpl, true
+		}
+	}
+	return nil, false
+}
+
+// matchLocale return the registered locale best matching Accept-Language header,
+// a language range also matches the locales of its base language
+func (s *Server) matchLocale(header string) string {
+	for _, r := range parseAcceptLanguage(header) {
+		if r.tag == "*" {
+			break
+		}
+		if _, ok := s.messages[r.tag]; ok {
+			return r.tag
+		}
+		if i := strings.IndexByte(r.tag, '-'); i > 0 {
+			if _, ok := s.messages[r.tag[:i]]; ok {
+				return r.tag[:i]
+			}
+		}
+	}
+	return s.defaultLocale
+}
+
+// parseAcceptLanguage parse Accept-Language header into language ranges ordered
+// by quality, ranges with zero quality are dropped
+func parseAcceptLanguage(header string) []languageRange {
+	var ranges []languageRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(params[0]))
+		if tag == "" {
+			continue
+		}
+
+		r := languageRange{tag: tag, q: 1}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q >= 0 && q <= 1 {
+					r.q = q
+				}
+			}
+		}
+		if r.q > 0 {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
@@ -0,0 +1,113 @@
+package zen
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := parseAcceptLanguage("fr-CA, fr;q=0.9, en;q=0.8, de;q=0, *;q=0.5")
+	want := []languageRange{{"fr-ca", 1}, {"fr", 0.9}, {"en", 0.8}, {"*", 0.5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseAcceptLanguage() = %v, want %v", got, want)
+	}
+}
+
+func TestContext_Locale(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		resolver LocaleResolver
+		want     string
+	}{
+		{"exact", "fr-CA, en;q=0.5", nil, "fr-ca"},
+		{"base language", "de-AT, fr;q=0.5", nil, "de"},
+		{"quality", "de;q=0.1, fr;q=0.9", nil, "fr"},
+		{"default", "es, it", nil, "en"},
+		{"empty", "", nil, "en"},
+		{"resolver", "de", func(c *Context) string { return c.Param("lang") }, "fr"},
+		{"resolver fallback", "de", func(c *Context) string { return "" }, "de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			for _, l := range []string{"en", "de", "fr", "fr-CA"} {
+				s.RegisterMessages(l, map[string]string{})
+			}
+			s.SetLocaleResolver(tt.resolver)
+
+			var got string
+			s.Get("/:lang", func(c *Context) {
+				got = c.Locale()
+			})
+			req := httptest.NewRequest(GET, "/fr", nil)
+			req.Header.Set(acceptLanguage, tt.header)
+			s.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("Locale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContext_Bind_LocalizedMessages(t *testing.T) {
+	type input struct {
+		Name string `form:"name" valid:"min=3"`
+		Mail string `form:"mail" valid:"email" msg:"invalid_mail"`
+		Age  int    `form:"age" valid:"min=18"`
+	}
+
+	s := New()
+	if err := s.RegisterMessages("en", map[string]string{
+		"min":          "{{.Field}} must be at least {{.Param}}",
+		"invalid_mail": "{{.Value}} is not an email",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterMessages("fr", map[string]string{
+		"min": "{{.Key}} doit faire au moins {{.Param}}",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RegisterMessages("fr", map[string]string{"bad": "{{.Field"}); err == nil {
+		t.Error("RegisterMessages() of invalid template error = nil")
+	}
+
+	var err error
+	var locale string
+	s.Get("/", func(c *Context) {
+		if locale != "" {
+			c.SetLocale(locale)
+		}
+		err = c.Bind(&input{})
+	})
+
+	tests := []struct {
+		header string
+		locale string
+		want   []string
+	}{
+		{"fr-FR", "", []string{"name doit faire au moins 3", "gopher is not an email", "age doit faire au moins 18"}},
+		{"en", "", []string{"Name must be at least 3", "gopher is not an email", "Age must be at least 18"}},
+		{"en", "fr", []string{"name doit faire au moins 3", "gopher is not an email", "age doit faire au moins 18"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header+tt.locale, func(t *testing.T) {
+			locale = tt.locale
+			req := httptest.NewRequest(GET, "/?name=go&mail=gopher&age=3", nil)
+			req.Header.Set(acceptLanguage, tt.header)
+			s.ServeHTTP(httptest.NewRecorder(), req)
+
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != len(tt.want) {
+				t.Fatalf("Bind() error = %v", err)
+			}
+			for i, e := range errs {
+				if e.Message != tt.want[i] {
+					t.Errorf("Message = %q, want %q", e.Message, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -4,3 +4,16 @@ package zen
 func (s *Server) Filter(filter HandlerFunc) {
 	s.filters = append(s.filters, filter)
 }
+
+// Chain combine handlers into one HandlerFunc, which runs them in order
+// until one of them writes response, so that filters can be attached to a single route
+func Chain(handlers ...HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		for _, h := range handlers {
+			h(c)
+			if c.rw.written {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package zen
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	accept    = "Accept"
+	vary      = "Vary"
+	textPlain = "text/plain"
+)
+
+// acceptRange is a media range of Accept header
+type acceptRange struct {
+	mainType string
+	subType  string
+	q        float64
+}
+
+// negotiate pick the renderer with the highest quality, ties are broken by
+// the specificity of the matched range, then by registration order
+func negotiate(header string, renderers []namedRenderer) (Renderer, bool) {
+	if len(renderers) == 0 {
+		return nil, false
+	}
+	if strings.TrimSpace(header) == "" {
+		return renderers[0].Renderer, true
+	}
+
+	ranges := parseAccept(header)
+	best, bestQ, bestSpecificity := -1, 0.0, -1
+	for i, r := range renderers {
+		mediaType := r.ContentType()
+		if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+			mediaType = mediaType[:i]
+		}
+		q, specificity := acceptQuality(ranges, strings.ToLower(strings.TrimSpace(mediaType)))
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = i, q, specificity
+		}
+	}
+	if best < 0 {
+		return nil, false
+	}
+	return renderers[best].Renderer, true
+}
+
+// acceptQuality return the q value of the most specific range matching mediaType
+func acceptQuality(ranges []acceptRange, mediaType string) (q float64, specificity int) {
+	i := strings.IndexByte(mediaType, '/')
+	mainType, subType := mediaType[:i], mediaType[i+1:]
+
+	specificity = -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.mainType == mainType && r.subType == subType:
+			s = 2
+		case r.mainType == mainType && r.subType == "*":
+			s = 1
+		case r.mainType == "*" && r.subType == "*":
+			s = 0
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return
+}
+
+// parseAccept parse Accept header into media ranges, invalid ranges are ignored
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+		i := strings.IndexByte(mediaRange, '/')
+		if i <= 0 || i == len(mediaRange)-1 {
+			continue
+		}
+
+		r := acceptRange{mainType: mediaRange[:i], subType: mediaRange[i+1:], q: 1}
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.ToLower(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q >= 0 && q <= 1 {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges
+}
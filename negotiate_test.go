@@ -0,0 +1,69 @@
+package zen
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_Negotiate(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+	tests := []struct {
+		name            string
+		accept          string
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{"no accept", "", http.StatusCreated, applicationJSON, "{\"name\":\"zen\"}\n"},
+		{"any", "*/*", http.StatusCreated, applicationJSON, "{\"name\":\"zen\"}\n"},
+		{"xml", "application/xml", http.StatusCreated, applicationXML, "<user><name>zen</name></user>"},
+		{"q values", "application/json;q=0.5, text/plain;q=0.8", http.StatusCreated, textPlain, "{zen}"},
+		{"specific beats wildcard", "text/*;q=0.9, text/xml", http.StatusCreated, textXML, "<user><name>zen</name></user>"},
+		{"text xml", "text/xml", http.StatusCreated, textXML, "<user><name>zen</name></user>"},
+		{"media type", "application/vnd.zen", http.StatusCreated, "application/vnd.zen", "zen"},
+		{"excluded", "*/*, application/json;q=0", http.StatusCreated, applicationXML, "<user><name>zen</name></user>"},
+		{"user registered", "text/csv, application/json;q=0.1", http.StatusCreated, "text/csv", "name\nzen\n"},
+		{"not acceptable", "image/png", http.StatusNotAcceptable, "text/plain; charset=utf-8", "Not Acceptable\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.RegisterRenderer("csv", NewRenderer("text/csv", func(w io.Writer, data interface{}) error {
+				_, err := io.WriteString(w, "name\n"+data.(user).Name+"\n")
+				return err
+			}))
+			s.RegisterMediaType("application/vnd.zen", func(w io.Writer, data interface{}) error {
+				_, err := io.WriteString(w, data.(user).Name)
+				return err
+			})
+			s.Get("/", func(c *Context) {
+				if err := c.Negotiate(http.StatusCreated, user{"zen"}); err != nil {
+					t.Errorf("Negotiate() error = %v", err)
+				}
+			})
+
+			req := httptest.NewRequest(GET, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set(accept, tt.accept)
+			}
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got := rw.Header().Get(contentType); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := rw.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if got := rw.Header().Get(vary); got != accept {
+				t.Errorf("Vary = %q, want %q", got, accept)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package zen
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+)
+
+var (
+	// ErrMissingParam is returned by typed param accessors when route has no param with the key
+	ErrMissingParam = errors.New("zen: missing url param")
+	// ErrInvalidUUID is returned when a string is not a UUID in the canonical form
+	ErrInvalidUUID = errors.New("zen: invalid uuid")
+)
+
+// UUID is a RFC 4122 UUID
+type UUID [16]byte
+
+// ParseUUID parse s in the canonical form, such as 6ba7b810-9dad-11d1-80b4-00c04fd430c8
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, ErrInvalidUUID
+	}
+	src := []byte(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+	if _, err := hex.Decode(u[:], src); err != nil {
+		return u, ErrInvalidUUID
+	}
+	return u, nil
+}
+
+// String return u in the canonical form
+func (u UUID) String() string {
+	buf := make([]byte, 36)
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf)
+}
+
+// MarshalText implement encoding.TextMarshaler
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText implement encoding.TextUnmarshaler
+func (u *UUID) UnmarshalText(text []byte) error {
+	parsed, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// SetUseRawPath set whether routes are matched against request's escaped path, so that
+// escaped slashes such as %2F stay in param values instead of splitting segments.
+// Param values are unescaped, and may contain "/" or "..", so never join them into
+// file paths unchecked. Static segments must be matched as they are escaped then,
+// such as /admin/us%65rs no longer matches /admin/users. It is disabled by default.
+func (s *Server) SetUseRawPath(enabled bool) {
+	s.useRawPath = enabled
+}
+
+// Params return url params of request in the order of route pattern,
+// it is only valid until the handler returns
+func (c *Context) Params() Params {
+	return c.params
+}
+
+// ParamInt return url param with given key as int
+func (c *Context) ParamInt(key string) (int, error) {
+	value, ok := c.params.lookup(key)
+	if !ok {
+		return 0, ErrMissingParam
+	}
+	return strconv.Atoi(value)
+}
+
+// ParamUint return url param with given key as uint
+func (c *Context) ParamUint(key string) (uint, error) {
+	value, ok := c.params.lookup(key)
+	if !ok {
+		return 0, ErrMissingParam
+	}
+	u, err := strconv.ParseUint(value, 10, 0)
+	return uint(u), err
+}
+
+// ParamUUID return url param with given key as UUID
+func (c *Context) ParamUUID(key string) (UUID, error) {
+	value, ok := c.params.lookup(key)
+	if !ok {
+		return UUID{}, ErrMissingParam
+	}
+	return ParseUUID(value)
+}
+
+// unescapeParams unescape values of params routed on raw path,
+// invalid escapes are kept as they are
+func unescapeParams(params Params) {
+	for i := range params {
+		if value, err := url.PathUnescape(params[i].Value); err == nil {
+			params[i].Value = value
+		}
+	}
+}
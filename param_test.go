@@ -0,0 +1,114 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseUUID(t *testing.T) {
+	tests := []struct {
+		s       string
+		wantErr bool
+	}{
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", false},
+		{"6BA7B810-9DAD-11D1-80B4-00C04FD430C8", false},
+		{"6ba7b8109dad11d180b400c04fd430c8", true},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430cz", true},
+		{"6ba7b810-9dad-11d1-80b4_00c04fd430c8", true},
+		{"", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			u, err := ParseUUID(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUUID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && u.String() != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" {
+				t.Errorf("String() = %s", u)
+			}
+		})
+	}
+}
+
+func TestContext_TypedParams(t *testing.T) {
+	s := New()
+	var params Params
+	s.Get("/users/:id/:uuid/*path", func(c *Context) {
+		params = append(params, c.Params()...)
+
+		if got, err := c.ParamInt("id"); got != -42 || err != nil {
+			t.Errorf("ParamInt() = %v, %v", got, err)
+		}
+		if _, err := c.ParamUint("id"); err == nil {
+			t.Error("ParamUint() of negative error = nil")
+		}
+		if _, err := c.ParamInt("missing"); err != ErrMissingParam {
+			t.Errorf("ParamInt() of missing error = %v", err)
+		}
+		if got, err := c.ParamUUID("uuid"); got.String() != "6ba7b810-9dad-11d1-80b4-00c04fd430c8" || err != nil {
+			t.Errorf("ParamUUID() = %v, %v", got, err)
+		}
+		if _, err := c.ParamUUID("id"); err != ErrInvalidUUID {
+			t.Errorf("ParamUUID() of invalid error = %v", err)
+		}
+	})
+	s.Get("/files/:name", func(c *Context) {
+		params = append(params, c.Params()...)
+	})
+
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/users/-42/6ba7b810-9dad-11d1-80b4-00c04fd430c8/a/b", nil))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/files/caf%C3%A9", nil))
+
+	want := Params{
+		{Key: "id", Value: "-42"},
+		{Key: "uuid", Value: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{Key: "path", Value: "/a/b"},
+		{Key: "name", Value: "café"},
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("Params() = %+v, want %+v", params, want)
+	}
+}
+
+func TestServer_SetUseRawPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      bool
+		path     string
+		wantCode int
+		want     string
+	}{
+		{"decoded", false, "/files/caf%C3%A9", http.StatusOK, "café"},
+		{"decoded slash", false, "/files/a%2Fb", http.StatusNotFound, ""},
+		{"decoded dot segments", false, "/files/%2E%2E%2Fetc%2Fpasswd", http.StatusNotFound, ""},
+		{"decoded static", false, "/admin/us%65rs", http.StatusOK, ""},
+		{"raw", true, "/files/caf%C3%A9", http.StatusOK, "café"},
+		{"raw slash", true, "/files/a%2Fb%20c", http.StatusOK, "a/b c"},
+		{"raw static", true, "/admin/us%65rs", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			s := New()
+			s.SetUseRawPath(tt.raw)
+			s.Get("/files/:name", func(c *Context) {
+				got = c.Param("name")
+				c.WriteStatus(http.StatusOK)
+			})
+			s.Get("/admin/users", func(c *Context) {
+				c.WriteStatus(http.StatusOK)
+			})
+
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, httptest.NewRequest(GET, tt.path, nil))
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got != tt.want {
+				t.Errorf("Param() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
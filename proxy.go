@@ -0,0 +1,199 @@
+package zen
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	forwarded       = "Forwarded"
+	xForwardedFor   = "X-Forwarded-For"
+	xForwardedProto = "X-Forwarded-Proto"
+	xForwardedHost  = "X-Forwarded-Host"
+	xRealIP         = "X-Real-IP"
+)
+
+// SetTrustedProxies set CIDRs or IPs of proxies whose Forwarded, X-Forwarded-*
+// and X-Real-IP headers are honoured by Context.ClientIP, Scheme and Host
+func (s *Server) SetTrustedProxies(proxies ...string) error {
+	nets, err := parseCIDRs(proxies)
+	if err != nil {
+		return err
+	}
+	s.trustedProxies = nets
+	return nil
+}
+
+// ClientIP return the ip of client, forwarding headers are honoured only
+// when the request comes from a trusted proxy
+func (c *Context) ClientIP() string {
+	c.resolveProxy()
+	return c.clientIP
+}
+
+// Scheme return the scheme, http or https, requested by client
+func (c *Context) Scheme() string {
+	c.resolveProxy()
+	return c.scheme
+}
+
+// Host return the host requested by client
+func (c *Context) Host() string {
+	c.resolveProxy()
+	return c.host
+}
+
+// resolveProxy resolve client's ip, scheme and host once per request
+func (c *Context) resolveProxy() {
+	if c.proxyResolved {
+		return
+	}
+	c.proxyResolved = true
+
+	c.clientIP = remoteIP(c.Req.RemoteAddr)
+	c.host = c.Req.Host
+	c.scheme = "http"
+	if c.Req.TLS != nil {
+		c.scheme = "https"
+	}
+	if !c.server.trustedProxy(c.clientIP) {
+		return
+	}
+
+	header := c.Req.Header
+	if fwd := header.Get(forwarded); fwd != "" {
+		c.resolveForwarded(fwd)
+		return
+	}
+
+	if xff := header.Get(xForwardedFor); xff != "" {
+		hops := strings.Split(xff, ",")
+		c.clientIP = c.server.untrustedHop(len(hops), func(i int) string {
+			return strings.TrimSpace(hops[i])
+		})
+	} else if ip := strings.TrimSpace(header.Get(xRealIP)); ip != "" {
+		c.clientIP = ip
+	}
+	if proto := firstHeaderValue(header.Get(xForwardedProto)); proto != "" {
+		c.scheme = strings.ToLower(proto)
+	}
+	if host := firstHeaderValue(header.Get(xForwardedHost)); host != "" {
+		c.host = host
+	}
+}
+
+// resolveForwarded resolve RFC 7239 Forwarded header, the element which names the
+// nearest untrusted hop describes the request that hop sent
+func (c *Context) resolveForwarded(fwd string) {
+	var elements []map[string]string
+	for _, element := range strings.Split(fwd, ",") {
+		pairs := make(map[string]string)
+		for _, pair := range strings.Split(element, ";") {
+			i := strings.IndexByte(pair, '=')
+			if i < 0 {
+				continue
+			}
+			key := strings.ToLower(strings.TrimSpace(pair[:i]))
+			pairs[key] = strings.Trim(strings.TrimSpace(pair[i+1:]), `"`)
+		}
+		elements = append(elements, pairs)
+	}
+
+	index := len(elements) - 1
+	c.clientIP = c.server.untrustedHop(len(elements), func(i int) string {
+		index = i
+		return forwardedNode(elements[i]["for"])
+	})
+	if proto := elements[index]["proto"]; proto != "" {
+		c.scheme = strings.ToLower(proto)
+	}
+	if host := elements[index]["host"]; host != "" {
+		c.host = host
+	}
+}
+
+// untrustedHop walk hops from the nearest one, and return the first one which is not
+// a trusted proxy, or the farthest one if all of them are trusted
+func (s *Server) untrustedHop(n int, hop func(i int) string) string {
+	ip := ""
+	for i := n - 1; i >= 0; i-- {
+		ip = hop(i)
+		if !s.trustedProxy(ip) {
+			break
+		}
+	}
+	return ip
+}
+
+func (s *Server) trustedProxy(ip string) bool {
+	if len(s.trustedProxies) == 0 {
+		return false
+	}
+	return containsIP(s.trustedProxies, net.ParseIP(ip))
+}
+
+// forwardedNode strip port and brackets from a Forwarded node, such as "[2001:db8::1]:4711"
+func forwardedNode(node string) string {
+	if strings.HasPrefix(node, "[") {
+		if i := strings.IndexByte(node, ']'); i > 0 {
+			return node[1:i]
+		}
+	}
+	if strings.Count(node, ":") == 1 {
+		return node[:strings.IndexByte(node, ':')]
+	}
+	return node
+}
+
+func firstHeaderValue(v string) string {
+	if i := strings.IndexByte(v, ','); i >= 0 {
+		v = v[:i]
+	}
+	return strings.TrimSpace(v)
+}
+
+// remoteIP strip port from addr
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// parseCIDRs parse CIDRs, a single IP is treated as a /32 or /128 network
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: cidr}
+			}
+			bits := 8 * net.IPv6len
+			if v4 := ip.To4(); v4 != nil {
+				ip, bits = v4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
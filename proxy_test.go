@@ -0,0 +1,88 @@
+package zen
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_ClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		headers    map[string]string
+		wantIP     string
+		wantScheme string
+		wantHost   string
+	}{
+		{
+			"direct", "192.0.2.1:1234", false,
+			map[string]string{xForwardedFor: "198.51.100.1", xForwardedProto: "https"},
+			"192.0.2.1", "http", "example.com",
+		},
+		{
+			"direct tls", "192.0.2.1:1234", true, nil,
+			"192.0.2.1", "https", "example.com",
+		},
+		{
+			"x-forwarded-for", "10.0.0.1:1234", false,
+			map[string]string{xForwardedFor: "203.0.113.9, 198.51.100.1, 10.0.0.2", xForwardedProto: "https", xForwardedHost: "api.example.com"},
+			"198.51.100.1", "https", "api.example.com",
+		},
+		{
+			"all trusted", "10.0.0.1:1234", false,
+			map[string]string{xForwardedFor: "10.0.0.3, 10.0.0.2"},
+			"10.0.0.3", "http", "example.com",
+		},
+		{
+			"x-real-ip", "10.0.0.1:1234", false,
+			map[string]string{xRealIP: "198.51.100.1"},
+			"198.51.100.1", "http", "example.com",
+		},
+		{
+			"forwarded", "[fd00::1]:1234", false,
+			map[string]string{forwarded: `for=203.0.113.9;proto=http, for="[2001:db8:cafe::17]:4711";proto=https;host=api.example.com, for=10.0.0.2`},
+			"2001:db8:cafe::17", "https", "api.example.com",
+		},
+		{
+			"forwarded wins", "10.0.0.1:1234", false,
+			map[string]string{forwarded: "for=198.51.100.1", xForwardedFor: "203.0.113.9"},
+			"198.51.100.1", "http", "example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			if err := s.SetTrustedProxies("10.0.0.0/8", "fd00::1"); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(GET, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			c := s.getContext(httptest.NewRecorder(), req)
+			defer s.putBackContext(c)
+
+			if got := c.ClientIP(); got != tt.wantIP {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.wantIP)
+			}
+			if got := c.Scheme(); got != tt.wantScheme {
+				t.Errorf("Scheme() = %q, want %q", got, tt.wantScheme)
+			}
+			if got := c.Host(); got != tt.wantHost {
+				t.Errorf("Host() = %q, want %q", got, tt.wantHost)
+			}
+		})
+	}
+}
+
+func TestServer_SetTrustedProxies(t *testing.T) {
+	if err := New().SetTrustedProxies("not an ip"); err == nil {
+		t.Errorf("SetTrustedProxies() with invalid ip should fail")
+	}
+}
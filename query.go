@@ -0,0 +1,68 @@
+package zen
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingQuery is returned by typed query accessors when key is absent from query string
+var ErrMissingQuery = errors.New("zen: missing query parameter")
+
+// queryValues return request's query string, which is parsed once per request
+func (c *Context) queryValues() url.Values {
+	if c.query == nil {
+		c.query = c.Req.URL.Query()
+	}
+	return c.query
+}
+
+// Query return the first value of key in query string, request's body is untouched
+func (c *Context) Query(key string) string {
+	return c.queryValues().Get(key)
+}
+
+// QueryDefault return the first value of key in query string, or def if key is absent
+func (c *Context) QueryDefault(key, def string) string {
+	if values, ok := c.queryValues()[key]; ok {
+		return values[0]
+	}
+	return def
+}
+
+// QueryArray return all values of key in query string, such as [1 2] of ?id=1&id=2
+func (c *Context) QueryArray(key string) []string {
+	return c.queryValues()[key]
+}
+
+// QueryMap return values of keys in the form of key[name], such as {a:1 b:2}
+// of ?filter[a]=1&filter[b]=2 with key filter
+func (c *Context) QueryMap(key string) map[string]string {
+	m := make(map[string]string)
+	prefix := key + "["
+	for k, values := range c.queryValues() {
+		if strings.HasPrefix(k, prefix) && strings.HasSuffix(k, "]") && len(k) > len(prefix) {
+			m[k[len(prefix):len(k)-1]] = values[0]
+		}
+	}
+	return m
+}
+
+// QueryInt return the first value of key in query string as int
+func (c *Context) QueryInt(key string) (int, error) {
+	values, ok := c.queryValues()[key]
+	if !ok {
+		return 0, ErrMissingQuery
+	}
+	return strconv.Atoi(values[0])
+}
+
+// QueryBool return the first value of key in query string as bool, see strconv.ParseBool
+func (c *Context) QueryBool(key string) (bool, error) {
+	values, ok := c.queryValues()[key]
+	if !ok {
+		return false, ErrMissingQuery
+	}
+	return strconv.ParseBool(values[0])
+}
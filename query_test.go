@@ -0,0 +1,60 @@
+package zen
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestContext_Query(t *testing.T) {
+	s := New()
+	var c *Context
+	s.Route(POST, "/", func(ctx *Context) {
+		c = ctx
+
+		if got := c.Query("name"); got != "zen" {
+			t.Errorf("Query() = %q", got)
+		}
+		if got := c.Query("body"); got != "" {
+			t.Errorf("Query() of body value = %q", got)
+		}
+		if got := c.QueryDefault("empty", "def"); got != "" {
+			t.Errorf("QueryDefault() of empty value = %q", got)
+		}
+		if got := c.QueryDefault("missing", "def"); got != "def" {
+			t.Errorf("QueryDefault() of missing value = %q", got)
+		}
+		if got := c.QueryArray("id"); !reflect.DeepEqual(got, []string{"1", "2"}) {
+			t.Errorf("QueryArray() = %v", got)
+		}
+		if got := c.QueryMap("filter"); !reflect.DeepEqual(got, map[string]string{"a": "1", "b": "x"}) {
+			t.Errorf("QueryMap() = %v", got)
+		}
+		if got, err := c.QueryInt("id"); got != 1 || err != nil {
+			t.Errorf("QueryInt() = %v, %v", got, err)
+		}
+		if _, err := c.QueryInt("name"); err == nil {
+			t.Error("QueryInt() of non integer error = nil")
+		}
+		if _, err := c.QueryInt("missing"); err != ErrMissingQuery {
+			t.Errorf("QueryInt() of missing value error = %v", err)
+		}
+		if got, err := c.QueryBool("debug"); !got || err != nil {
+			t.Errorf("QueryBool() = %v, %v", got, err)
+		}
+		if _, err := c.QueryBool("missing"); err != ErrMissingQuery {
+			t.Errorf("QueryBool() of missing value error = %v", err)
+		}
+		if c.parsed || c.bodyPrepared {
+			t.Error("query accessors touched request body")
+		}
+	})
+	req := httptest.NewRequest(POST, "/?name=zen&empty=&id=1&id=2&filter[a]=1&filter[b]=x&filter=y&filters[c]=z&debug=true", strings.NewReader("body=1"))
+	req.Header.Set(contentType, applicationForm)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	if c == nil || c.query != nil {
+		t.Error("query cache is not reset when context is put back")
+	}
+}
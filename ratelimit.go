@@ -0,0 +1,267 @@
+package zen
+
+import (
+	"hash/fnv"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	rateLimitLimit     = "RateLimit-Limit"
+	rateLimitRemaining = "RateLimit-Remaining"
+	rateLimitReset     = "RateLimit-Reset"
+	retryAfter         = "Retry-After"
+
+	rateLimitShards     = 32
+	rateLimitSweepEvery = 1024
+)
+
+type (
+	// RateLimitState is the per key state of a rate limit algorithm,
+	// it is persisted by a RateLimitStore between requests
+	RateLimitState struct {
+		// Tokens left in the bucket, used by token bucket
+		Tokens float64
+		// Count of requests in current window, used by sliding window
+		Count int
+		// PrevCount of requests in previous window, used by sliding window
+		PrevCount int
+		// Start is the last refill time of token bucket, or current window's start of sliding window
+		Start time.Time
+	}
+
+	// RateLimitResult is the decision made for a request
+	RateLimitResult struct {
+		Allowed    bool
+		Limit      int
+		Remaining  int
+		Reset      time.Duration
+		RetryAfter time.Duration
+	}
+
+	// RateLimitAlgorithm decide whether a request is allowed by updating state
+	RateLimitAlgorithm interface {
+		// Take consume one request from state at now
+		Take(state *RateLimitState, now time.Time) RateLimitResult
+		// TTL is how long an idle state must be kept by store
+		TTL() time.Duration
+	}
+
+	// RateLimitStore persist RateLimitState by key, Take must load the state,
+	// apply algorithm and save the state atomically
+	RateLimitStore interface {
+		Take(key string, algorithm RateLimitAlgorithm, now time.Time) (RateLimitResult, error)
+	}
+
+	// RateLimitConfig configure RateLimit middleware
+	RateLimitConfig struct {
+		// Algorithm is required, see TokenBucket and SlidingWindow
+		Algorithm RateLimitAlgorithm
+		// Store defaults to a new MemoryRateLimitStore
+		Store RateLimitStore
+		// KeyFunc defaults to RateLimitByIP
+		KeyFunc func(*Context) string
+	}
+)
+
+// RateLimit return a middleware which limit requests by key, it responds 429 with
+// Retry-After when the limit is exceeded and sets RateLimit-* headers on every response.
+// Requests are let through if the store fails.
+func RateLimit(config RateLimitConfig) HandlerFunc {
+	assert(config.Algorithm != nil, "rate limit algorithm cannot be nil")
+	if config.Store == nil {
+		config.Store = NewMemoryRateLimitStore()
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = RateLimitByIP
+	}
+
+	return func(c *Context) {
+		res, err := config.Store.Take(config.KeyFunc(c), config.Algorithm, time.Now())
+		if err != nil {
+			return
+		}
+
+		header := c.rw.Header()
+		header.Set(rateLimitLimit, strconv.Itoa(res.Limit))
+		header.Set(rateLimitRemaining, strconv.Itoa(res.Remaining))
+		header.Set(rateLimitReset, strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			header.Set(retryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			http.Error(c.rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}
+	}
+}
+
+// RateLimitByIP use client's ip as rate limit key, see Context.ClientIP
+func RateLimitByIP(c *Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// RateLimitByHeader use the value of header, such as an api key, as rate limit key,
+// and fallback to client's ip when the header is absent
+func RateLimitByHeader(header string) func(*Context) string {
+	return func(c *Context) string {
+		if v := c.Req.Header.Get(header); v != "" {
+			return "key:" + v
+		}
+		return RateLimitByIP(c)
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// -----------------------------------------------------------------------------
+// Algorithms
+
+type tokenBucket struct {
+	limit int
+	rate  float64 // tokens per nanosecond
+	per   time.Duration
+}
+
+// TokenBucket allow bursts of limit requests, refilled at limit tokens per duration
+func TokenBucket(limit int, per time.Duration) RateLimitAlgorithm {
+	assert(limit > 0 && per > 0, "token bucket limit and duration must be positive")
+	return &tokenBucket{limit: limit, rate: float64(limit) / float64(per), per: per}
+}
+
+func (b *tokenBucket) Take(state *RateLimitState, now time.Time) RateLimitResult {
+	capacity := float64(b.limit)
+	if state.Start.IsZero() {
+		state.Tokens = capacity
+	} else if elapsed := now.Sub(state.Start); elapsed > 0 {
+		state.Tokens = math.Min(capacity, state.Tokens+float64(elapsed)*b.rate)
+	}
+	state.Start = now
+
+	res := RateLimitResult{Limit: b.limit}
+	if state.Tokens >= 1 {
+		state.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - state.Tokens) / b.rate)
+	}
+	res.Remaining = int(state.Tokens)
+	res.Reset = time.Duration((capacity - state.Tokens) / b.rate)
+	return res
+}
+
+func (b *tokenBucket) TTL() time.Duration {
+	return b.per
+}
+
+type slidingWindow struct {
+	limit  int
+	window time.Duration
+}
+
+// SlidingWindow allow limit requests in any window, estimated by weighting
+// the previous fixed window's count
+func SlidingWindow(limit int, window time.Duration) RateLimitAlgorithm {
+	assert(limit > 0 && window > 0, "sliding window limit and window must be positive")
+	return &slidingWindow{limit: limit, window: window}
+}
+
+func (w *slidingWindow) Take(state *RateLimitState, now time.Time) RateLimitResult {
+	start := now.Truncate(w.window)
+	if !state.Start.Equal(start) {
+		if state.Start.Equal(start.Add(-w.window)) {
+			state.PrevCount = state.Count
+		} else {
+			state.PrevCount = 0
+		}
+		state.Count = 0
+		state.Start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(w.window)
+	estimated := float64(state.PrevCount)*weight + float64(state.Count)
+
+	res := RateLimitResult{Limit: w.limit, Reset: w.window - elapsed}
+	if estimated+1 <= float64(w.limit) {
+		state.Count++
+		estimated++
+		res.Allowed = true
+	} else if state.Count < w.limit && state.PrevCount > 0 {
+		// wait until previous window's weight decays enough for one more request
+		free := float64(w.limit-1-state.Count) / float64(state.PrevCount)
+		res.RetryAfter = time.Duration((1-free)*float64(w.window)) - elapsed
+	} else {
+		res.RetryAfter = w.window - elapsed
+	}
+	res.Remaining = int(math.Max(0, float64(w.limit)-math.Ceil(estimated)))
+	return res
+}
+
+func (w *slidingWindow) TTL() time.Duration {
+	return 2 * w.window
+}
+
+// -----------------------------------------------------------------------------
+// In memory store
+
+type (
+	// MemoryRateLimitStore is a RateLimitStore kept in process memory,
+	// sharded to reduce lock contention
+	MemoryRateLimitStore struct {
+		shards [rateLimitShards]rateLimitShard
+	}
+
+	rateLimitShard struct {
+		sync.Mutex
+		entries map[string]*rateLimitEntry
+		ops     int
+	}
+
+	rateLimitEntry struct {
+		state  RateLimitState
+		expire time.Time
+	}
+)
+
+// NewMemoryRateLimitStore create an empty MemoryRateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*rateLimitEntry)
+	}
+	return s
+}
+
+// Take implement RateLimitStore
+func (s *MemoryRateLimitStore) Take(key string, algorithm RateLimitAlgorithm, now time.Time) (RateLimitResult, error) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%rateLimitShards]
+
+	shard.Lock()
+	defer shard.Unlock()
+
+	e, ok := shard.entries[key]
+	if !ok || now.After(e.expire) {
+		e = &rateLimitEntry{}
+		shard.entries[key] = e
+	}
+	res := algorithm.Take(&e.state, now)
+	e.expire = now.Add(algorithm.TTL())
+
+	// drop idle entries once in a while
+	if shard.ops++; shard.ops%rateLimitSweepEvery == 0 {
+		for k, v := range shard.entries {
+			if now.After(v.expire) {
+				delete(shard.entries, k)
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,146 @@
+package zen
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRateLimitStore is a map backed RateLimitStore, standing in for a remote backend
+type fakeRateLimitStore struct {
+	sync.Mutex
+	states map[string]RateLimitState
+	err    error
+}
+
+func (s *fakeRateLimitStore) Take(key string, algorithm RateLimitAlgorithm, now time.Time) (RateLimitResult, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.err != nil {
+		return RateLimitResult{}, s.err
+	}
+	state := s.states[key]
+	res := algorithm.Take(&state, now)
+	s.states[key] = state
+	return res, nil
+}
+
+func TestTokenBucket_Take(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name          string
+		offset        time.Duration
+		wantAllowed   bool
+		wantRemaining int
+	}{
+		{"first", 0, true, 1},
+		{"second", 0, true, 0},
+		{"empty", 0, false, 0},
+		{"half refilled", 500 * time.Millisecond, true, 0},
+		{"refilled", 2500 * time.Millisecond, true, 1},
+	}
+	bucket := TokenBucket(2, time.Second)
+	var state RateLimitState
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := bucket.Take(&state, start.Add(tt.offset))
+			if res.Allowed != tt.wantAllowed || res.Remaining != tt.wantRemaining {
+				t.Errorf("Take() = %+v, want allowed %v remaining %d", res, tt.wantAllowed, tt.wantRemaining)
+			}
+			if !res.Allowed && res.RetryAfter <= 0 {
+				t.Errorf("Take() RetryAfter = %v, want positive", res.RetryAfter)
+			}
+		})
+	}
+}
+
+func TestSlidingWindow_Take(t *testing.T) {
+	start := time.Unix(1000, 0)
+	tests := []struct {
+		name        string
+		offset      time.Duration
+		wantAllowed bool
+	}{
+		{"1st", 0, true},
+		{"2nd", 100 * time.Millisecond, true},
+		{"3rd", 200 * time.Millisecond, false},
+		// previous window still weighs 2*0.75 = 1.5
+		{"next window early", 1250 * time.Millisecond, false},
+		// previous window weighs 2*0.25 = 0.5
+		{"next window late", 1750 * time.Millisecond, true},
+		{"idle", 5 * time.Second, true},
+	}
+	window := SlidingWindow(2, time.Second)
+	var state RateLimitState
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := window.Take(&state, start.Add(tt.offset))
+			if res.Allowed != tt.wantAllowed {
+				t.Errorf("Take() = %+v, want allowed %v", res, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		storeErr  error
+		requests  int
+		wantCode  int
+		wantRetry bool
+	}{
+		{"allowed", nil, 2, http.StatusNoContent, false},
+		{"limited", nil, 3, http.StatusTooManyRequests, true},
+		{"store failure", errors.New("unavailable"), 3, http.StatusNoContent, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Filter(RateLimit(RateLimitConfig{
+				Algorithm: TokenBucket(2, time.Minute),
+				Store:     &fakeRateLimitStore{states: map[string]RateLimitState{}, err: tt.storeErr},
+				KeyFunc:   RateLimitByHeader("X-API-Key"),
+			}))
+			s.Get("/", func(c *Context) {
+				c.WriteStatus(http.StatusNoContent)
+			})
+
+			var rw *httptest.ResponseRecorder
+			for i := 0; i < tt.requests; i++ {
+				rw = httptest.NewRecorder()
+				req := httptest.NewRequest(GET, "/", nil)
+				req.Header.Set("X-API-Key", "secret")
+				s.ServeHTTP(rw, req)
+			}
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got := rw.Header().Get(retryAfter) != ""; got != tt.wantRetry {
+				t.Errorf("Retry-After present = %v, want %v", got, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestMemoryRateLimitStore_Take(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	algorithm := TokenBucket(1, time.Second)
+	now := time.Unix(1000, 0)
+
+	if res, _ := store.Take("a", algorithm, now); !res.Allowed {
+		t.Errorf("first request of a should be allowed")
+	}
+	if res, _ := store.Take("a", algorithm, now); res.Allowed {
+		t.Errorf("second request of a should be limited")
+	}
+	if res, _ := store.Take("b", algorithm, now); !res.Allowed {
+		t.Errorf("first request of b should be allowed")
+	}
+	if res, _ := store.Take("a", algorithm, now.Add(2*time.Second)); !res.Allowed {
+		t.Errorf("expired state of a should be reset")
+	}
+}
@@ -0,0 +1,153 @@
+package zen
+
+import (
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// names of builtin renderers
+const (
+	RenderJSON = "json"
+	RenderXML  = "xml"
+	RenderASN1 = "asn1"
+	RenderText = "text"
+)
+
+// ErrRendererNotFound is returned when no renderer is registered with a name
+var ErrRendererNotFound = errors.New("zen: renderer not found")
+
+type (
+	// Renderer write data into response body in a format
+	Renderer interface {
+		// ContentType return the media type of response
+		ContentType() string
+		// Render encode data into w
+		Render(w io.Writer, data interface{}) error
+	}
+
+	// rendererFunc adapt a function into Renderer
+	rendererFunc struct {
+		contentType string
+		render      func(io.Writer, interface{}) error
+	}
+
+	// namedRenderer is an entry of server's renderer registry
+	namedRenderer struct {
+		name string
+		Renderer
+	}
+)
+
+// NewRenderer create a Renderer of contentType from render function
+func NewRenderer(contentType string, render func(w io.Writer, data interface{}) error) Renderer {
+	assert(render != nil, "render cannot be nil")
+	return &rendererFunc{contentType: contentType, render: render}
+}
+
+func (r *rendererFunc) ContentType() string {
+	return r.contentType
+}
+
+func (r *rendererFunc) Render(w io.Writer, data interface{}) error {
+	return r.render(w, data)
+}
+
+// RegisterRenderer register r with name, which replaces the renderer registered with
+// the same name, builtin ones included. Renderers are candidates of Context.Negotiate,
+// and those registered earlier are preferred when client accepts several equally.
+func (s *Server) RegisterRenderer(name string, r Renderer) {
+	assert(r != nil, "renderer cannot be nil")
+	assert(strings.Count(r.ContentType(), "/") == 1, "renderer content type must be in the form of type/subtype")
+	for i := range s.renderers {
+		if s.renderers[i].name == name {
+			s.renderers[i].Renderer = r
+			return
+		}
+	}
+	s.renderers = append(s.renderers, namedRenderer{name: name, Renderer: r})
+}
+
+// RegisterMediaType register render as the renderer of mediaType, named after mediaType,
+// it is a shorthand of RegisterRenderer(mediaType, NewRenderer(mediaType, render))
+func (s *Server) RegisterMediaType(mediaType string, render func(w io.Writer, data interface{}) error) {
+	mediaType = strings.ToLower(mediaType)
+	s.RegisterRenderer(mediaType, NewRenderer(mediaType, render))
+}
+
+// renderer return the renderer registered with name
+func (s *Server) renderer(name string) (Renderer, bool) {
+	for _, r := range s.renderers {
+		if r.name == name {
+			return r.Renderer, true
+		}
+	}
+	return nil, false
+}
+
+// registerDefaultRenderers register JSON, XML, ASN.1 and plain text renderers,
+// XML is negotiated as both application/xml and text/xml
+func (s *Server) registerDefaultRenderers() {
+	s.RegisterRenderer(RenderJSON, NewRenderer(applicationJSON, func(w io.Writer, data interface{}) error {
+		return json.NewEncoder(w).Encode(data)
+	}))
+	xmlRender := func(w io.Writer, data interface{}) error {
+		return xml.NewEncoder(w).Encode(data)
+	}
+	s.RegisterRenderer(RenderXML, NewRenderer(applicationXML, xmlRender))
+	s.RegisterMediaType(textXML, xmlRender)
+	s.RegisterRenderer(RenderASN1, NewRenderer(applicationASN1, func(w io.Writer, data interface{}) error {
+		bts, err := asn1.Marshal(data)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(bts)
+		return err
+	}))
+	s.RegisterRenderer(RenderText, NewRenderer(textPlain, func(w io.Writer, data interface{}) error {
+		_, err := fmt.Fprint(w, data)
+		return err
+	}))
+}
+
+// Render write data with status code using the renderer registered with name
+func (c *Context) Render(code int, name string, data interface{}) error {
+	r, ok := c.server.renderer(name)
+	if !ok {
+		return ErrRendererNotFound
+	}
+	c.WriteHeader(contentType, r.ContentType())
+	c.WriteStatus(code)
+	return r.Render(c.rw, data)
+}
+
+// render write data using the renderer registered with name, without writing status code
+func (c *Context) render(name string, data interface{}) error {
+	r, ok := c.server.renderer(name)
+	if !ok {
+		return ErrRendererNotFound
+	}
+	c.WriteHeader(contentType, r.ContentType())
+	return r.Render(c.rw, data)
+}
+
+// Negotiate write data with status code using the renderer which best matches request's
+// Accept header, it responds 406 if no registered renderer is acceptable
+func (c *Context) Negotiate(code int, data interface{}) error {
+	c.rw.Header().Add(vary, accept)
+
+	r, ok := negotiate(c.Req.Header.Get(accept), c.server.renderers)
+	if !ok {
+		http.Error(c.rw, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return nil
+	}
+
+	c.WriteHeader(contentType, r.ContentType())
+	c.WriteStatus(code)
+	return r.Render(c.rw, data)
+}
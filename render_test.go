@@ -0,0 +1,65 @@
+package zen
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_Render(t *testing.T) {
+	tests := []struct {
+		name            string
+		renderer        string
+		wantErr         error
+		wantCode        int
+		wantContentType string
+		wantBody        string
+	}{
+		{"json", RenderJSON, nil, http.StatusAccepted, applicationJSON, "\"zen\"\n"},
+		{"text", RenderText, nil, http.StatusAccepted, textPlain, "zen"},
+		{"custom", "upper", nil, http.StatusAccepted, "text/x-upper; charset=utf-8", "ZEN"},
+		{"not found", "yaml", ErrRendererNotFound, http.StatusOK, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.RegisterRenderer("upper", NewRenderer("text/x-upper; charset=utf-8", func(w io.Writer, data interface{}) error {
+				_, err := io.WriteString(w, "ZEN")
+				return err
+			}))
+			rw := httptest.NewRecorder()
+			c := s.getContext(rw, httptest.NewRequest(GET, "/", nil))
+			defer s.putBackContext(c)
+
+			if err := c.Render(http.StatusAccepted, tt.renderer, "zen"); err != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got := rw.Header().Get(contentType); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := rw.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestContext_JSON_customRenderer(t *testing.T) {
+	s := New()
+	s.RegisterRenderer(RenderJSON, NewRenderer("application/vnd.zen+json", func(w io.Writer, data interface{}) error {
+		_, err := io.WriteString(w, "{}")
+		return err
+	}))
+	rw := httptest.NewRecorder()
+	c := s.getContext(rw, httptest.NewRequest(GET, "/", nil))
+	defer s.putBackContext(c)
+
+	c.JSON("ignored")
+	if got := rw.Header().Get(contentType); got != "application/vnd.zen+json" {
+		t.Errorf("Content-Type = %q, want the replaced renderer's", got)
+	}
+}
@@ -1,6 +1,14 @@
 package zen
 
-import "net/http"
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ErrHijackNotSupported is returned by Hijack when the underlying ResponseWriter is not a http.Hijacker
+var ErrHijackNotSupported = errors.New("zen: response writer does not support hijacking")
 
 // -----------------------------------------------------------------------------
 // Simple wrapper around a ResponseWriter
@@ -32,3 +40,41 @@ func (w *responseWriter) WriteHeader(code int) {
 	w.written = true
 	w.writer.WriteHeader(code)
 }
+
+// Flush sends any buffered data to the client if the underlying
+// ResponseWriter is a http.Flusher, and sets `written` to true
+func (w *responseWriter) Flush() {
+	if f, ok := w.flusher(); ok {
+		w.written = true
+		f.Flush()
+	}
+}
+
+// flusher returns the underlying ResponseWriter as a http.Flusher,
+// ok is false if it can not flush, such as in a handler wrapped by Timeout
+func (w *responseWriter) flusher() (f http.Flusher, ok bool) {
+	f, ok = w.writer.(http.Flusher)
+	return
+}
+
+// Hijack lets the caller take over the connection if the underlying
+// ResponseWriter is a http.Hijacker, and sets `written` to true
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.writer.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrHijackNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push if the underlying ResponseWriter is a http.Pusher
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.writer.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
@@ -0,0 +1,107 @@
+package zen
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	cspNonceBytes       = 16
+	cspNoncePlaceholder = "{nonce}"
+)
+
+// SecureHeadersConfig configure SecureHeaders middleware, zero value fields are omitted
+type SecureHeadersConfig struct {
+	// HSTSMaxAge in seconds enables Strict-Transport-Security on https requests when positive
+	HSTSMaxAge            int
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	// ContentSecurityPolicy may contain {nonce}, which is replaced by a per request nonce,
+	// see Context.CSPNonce
+	ContentSecurityPolicy string
+	// CSPReportOnly sends Content-Security-Policy-Report-Only instead
+	CSPReportOnly bool
+	// FrameOptions is the value of X-Frame-Options, such as DENY or SAMEORIGIN
+	FrameOptions string
+	// ContentTypeNosniff sends X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool
+	ReferrerPolicy     string
+	PermissionsPolicy  string
+	// SSLRedirect redirect http requests to https
+	SSLRedirect bool
+	// SSLHost is the host of redirect, defaults to the host requested by client
+	SSLHost string
+}
+
+// SecureHeaders return a middleware which set security headers on every response,
+// and redirect http requests to https if SSLRedirect is set. The scheme requested
+// by client is honoured from trusted proxies, see Server.SetTrustedProxies
+func SecureHeaders(config SecureHeadersConfig) HandlerFunc {
+	hsts := ""
+	if config.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(config.HSTSMaxAge)
+		if config.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if config.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+	cspHeader := "Content-Security-Policy"
+	if config.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	useNonce := strings.Contains(config.ContentSecurityPolicy, cspNoncePlaceholder)
+
+	return func(c *Context) {
+		https := c.Scheme() == "https"
+		if config.SSLRedirect && !https {
+			config.redirect(c)
+			return
+		}
+
+		header := c.rw.Header()
+		if hsts != "" && https {
+			header.Set("Strict-Transport-Security", hsts)
+		}
+		if csp := config.ContentSecurityPolicy; csp != "" {
+			if useNonce {
+				c.cspNonce = randomToken(cspNonceBytes)
+				csp = strings.Replace(csp, cspNoncePlaceholder, c.cspNonce, -1)
+			}
+			header.Set(cspHeader, csp)
+		}
+		if config.FrameOptions != "" {
+			header.Set("X-Frame-Options", config.FrameOptions)
+		}
+		if config.ContentTypeNosniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+		if config.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", config.ReferrerPolicy)
+		}
+		if config.PermissionsPolicy != "" {
+			header.Set("Permissions-Policy", config.PermissionsPolicy)
+		}
+	}
+}
+
+// CSPNonce return the nonce of request's Content-Security-Policy set by SecureHeaders,
+// use it as nonce attribute of inline scripts and styles
+func (c *Context) CSPNonce() string {
+	return c.cspNonce
+}
+
+// redirect request to https, methods other than GET and HEAD are redirected with 308
+func (config *SecureHeadersConfig) redirect(c *Context) {
+	host := config.SSLHost
+	if host == "" {
+		host = c.Host()
+	}
+	code := http.StatusMovedPermanently
+	if c.Req.Method != GET && c.Req.Method != HEAD {
+		code = http.StatusPermanentRedirect
+	}
+	http.Redirect(c.rw, c.Req, "https://"+host+c.Req.URL.RequestURI(), code)
+}
@@ -0,0 +1,120 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	config := SecureHeadersConfig{
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		ContentSecurityPolicy: "script-src 'self' 'nonce-{nonce}'",
+		FrameOptions:          "DENY",
+		ContentTypeNosniff:    true,
+		ReferrerPolicy:        "same-origin",
+		PermissionsPolicy:     "geolocation=()",
+		SSLRedirect:           true,
+	}
+	tests := []struct {
+		name         string
+		method       string
+		remoteAddr   string
+		proto        string
+		wantCode     int
+		wantLocation string
+	}{
+		{"http get", GET, "192.0.2.1:1234", "", http.StatusMovedPermanently, "https://example.com/page?q=1"},
+		{"http post", POST, "192.0.2.1:1234", "", http.StatusPermanentRedirect, "https://example.com/page?q=1"},
+		{"proxied https", GET, "10.0.0.1:1234", "https", http.StatusOK, ""},
+		{"untrusted proxy header", GET, "192.0.2.1:1234", "https", http.StatusMovedPermanently, "https://example.com/page?q=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var nonce string
+			s := New()
+			s.SetTrustedProxies("10.0.0.0/8")
+			s.Filter(SecureHeaders(config))
+			s.Route(tt.method, "/page", func(c *Context) {
+				nonce = c.CSPNonce()
+				c.RawStr("ok")
+			})
+
+			req := httptest.NewRequest(tt.method, "/page?q=1", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Fatalf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got := rw.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+
+			want := map[string]string{
+				"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+				"Content-Security-Policy":   "script-src 'self' 'nonce-" + nonce + "'",
+				"X-Frame-Options":           "DENY",
+				"X-Content-Type-Options":    "nosniff",
+				"Referrer-Policy":           "same-origin",
+				"Permissions-Policy":        "geolocation=()",
+			}
+			for k, v := range want {
+				if got := rw.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+			if nonce == "" || strings.Contains(rw.Header().Get("Content-Security-Policy"), cspNoncePlaceholder) {
+				t.Errorf("CSPNonce() = %q, nonce placeholder is not replaced", nonce)
+			}
+		})
+	}
+}
+
+func TestSecureHeaders_SpoofedProxyHeaders(t *testing.T) {
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		wantCode     int
+		wantLocation string
+		wantHSTS     string
+	}{
+		{"untrusted client", "192.0.2.1:1234", http.StatusMovedPermanently, "https://example.com/", ""},
+		{"trusted proxy", "10.0.0.1:1234", http.StatusOK, "", "max-age=60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.SetTrustedProxies("10.0.0.0/8")
+			s.Filter(SecureHeaders(SecureHeadersConfig{HSTSMaxAge: 60, SSLRedirect: true}))
+			s.Get("/", func(c *Context) {
+				c.RawStr("ok")
+			})
+
+			req := httptest.NewRequest(GET, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-Proto", "https")
+			req.Header.Set("X-Forwarded-Host", "evil.example")
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if got := rw.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			if got := rw.Header().Get("Strict-Transport-Security"); got != tt.wantHSTS {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.wantHSTS)
+			}
+		})
+	}
+}
@@ -0,0 +1,431 @@
+package zen
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSessionMaxAge is the default max age in seconds of sessions
+	defaultSessionMaxAge = 86400 * 7
+	// sessionIDBytes is the count of random bytes of a session id
+	sessionIDBytes = 32
+)
+
+var (
+	// ErrNoSessionStore is returned when Sessions middleware is not used
+	ErrNoSessionStore = errors.New("zen: no session store, use Sessions middleware")
+	// ErrInvalidCookie is returned when a signed cookie can not be verified or decrypted
+	ErrInvalidCookie = errors.New("zen: invalid signed cookie")
+	// ErrCookieExpired is returned when a signed cookie is older than its max age
+	ErrCookieExpired = errors.New("zen: signed cookie expired")
+)
+
+type (
+	// Session hold values of a client between requests, values are
+	// serialized with encoding/json, so numbers are loaded as float64
+	Session struct {
+		Name   string
+		ID     string
+		Values map[string]interface{}
+		IsNew  bool
+
+		previousID string
+		destroyed  bool
+	}
+
+	// SessionOptions configure session cookies
+	SessionOptions struct {
+		Path     string
+		Domain   string
+		MaxAge   int
+		Secure   bool
+		HTTPOnly bool
+		SameSite http.SameSite
+	}
+
+	// SessionStore load and save sessions
+	SessionStore interface {
+		// Load return the session of request with name, or a new session if it is absent or invalid
+		Load(r *http.Request, name string) (*Session, error)
+		// Save persist session and write its cookie into response,
+		// it must delete the session of PreviousID and honour IsDestroyed
+		Save(rw http.ResponseWriter, s *Session) error
+	}
+
+	// CookieStore is a SessionStore which keeps values in a signed,
+	// and optionally encrypted, cookie
+	CookieStore struct {
+		Options SessionOptions
+		codecs  []cookieCodec
+	}
+
+	// MemorySessionStore is a SessionStore which keeps values in process memory,
+	// and only a signed session id in cookie
+	MemorySessionStore struct {
+		Options SessionOptions
+		codecs  []cookieCodec
+
+		mu       sync.Mutex
+		sessions map[string]memorySession
+	}
+
+	memorySession struct {
+		values map[string]interface{}
+		expire time.Time
+	}
+
+	// cookieCodec sign a cookie value with hashKey, and encrypt it with AES-GCM if block is set
+	cookieCodec struct {
+		hashKey []byte
+		block   cipher.AEAD
+	}
+)
+
+// Sessions return a middleware which make sessions named name of store available through Context
+func Sessions(name string, store SessionStore) HandlerFunc {
+	assert(store != nil, "session store cannot be nil")
+	return func(c *Context) {
+		c.sessionName = name
+		c.sessionStore = store
+	}
+}
+
+// Session return the session of request, which is loaded once per request
+func (c *Context) Session() (*Session, error) {
+	if c.session != nil {
+		return c.session, nil
+	}
+	if c.sessionStore == nil {
+		return nil, ErrNoSessionStore
+	}
+	s, err := c.sessionStore.Load(c.Req, c.sessionName)
+	if err != nil {
+		return nil, err
+	}
+	c.session = s
+	return s, nil
+}
+
+// SaveSession save the session of request, it must be called before writing body
+func (c *Context) SaveSession() error {
+	s, err := c.Session()
+	if err != nil {
+		return err
+	}
+	return c.sessionStore.Save(c.rw, s)
+}
+
+// RegenerateSession give the session of request a new id and keeps its values,
+// it should be called on login to prevent session fixation
+func (c *Context) RegenerateSession() error {
+	s, err := c.Session()
+	if err != nil {
+		return err
+	}
+	s.Regenerate()
+	return nil
+}
+
+// newSession create an empty session with a random id
+func newSession(name string) *Session {
+	return &Session{
+		Name:   name,
+		ID:     randomToken(sessionIDBytes),
+		Values: make(map[string]interface{}),
+		IsNew:  true,
+	}
+}
+
+// Regenerate give s a new id, the store deletes the previous one on Save
+func (s *Session) Regenerate() {
+	if s.previousID == "" && !s.IsNew {
+		s.previousID = s.ID
+	}
+	s.ID = randomToken(sessionIDBytes)
+}
+
+// Destroy mark s to be deleted and its cookie expired on Save
+func (s *Session) Destroy() {
+	s.destroyed = true
+	s.Values = make(map[string]interface{})
+}
+
+// PreviousID return the id replaced by Regenerate
+func (s *Session) PreviousID() string {
+	return s.previousID
+}
+
+// IsDestroyed report whether Destroy is called
+func (s *Session) IsDestroyed() bool {
+	return s.destroyed
+}
+
+// cookie return a cookie of s with value and options
+func (o SessionOptions) cookie(s *Session, value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     s.Name,
+		Value:    value,
+		Path:     o.Path,
+		Domain:   o.Domain,
+		MaxAge:   o.MaxAge,
+		Secure:   o.Secure,
+		HttpOnly: o.HTTPOnly,
+		SameSite: o.SameSite,
+	}
+	if o.MaxAge > 0 {
+		cookie.Expires = time.Now().Add(time.Duration(o.MaxAge) * time.Second)
+	}
+	if s.destroyed {
+		cookie.Value = ""
+		cookie.MaxAge = -1
+		cookie.Expires = time.Unix(1, 0)
+	}
+	return cookie
+}
+
+func defaultSessionOptions() SessionOptions {
+	return SessionOptions{
+		Path:     "/",
+		MaxAge:   defaultSessionMaxAge,
+		HTTPOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Cookie store
+
+// NewCookieStore create a CookieStore with pairs of hash key and block key.
+// Hash keys sign cookies with HMAC-SHA256, block keys are optional and encrypt cookies
+// with AES-GCM, they must be 16, 24 or 32 bytes long. The first pair encodes
+// cookies while all pairs decode them, so that keys can be rotated.
+func NewCookieStore(keyPairs ...[]byte) *CookieStore {
+	return &CookieStore{Options: defaultSessionOptions(), codecs: newCookieCodecs(keyPairs)}
+}
+
+// Load implement SessionStore
+func (store *CookieStore) Load(r *http.Request, name string) (*Session, error) {
+	s := newSession(name)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	data, err := decodeCookie(store.codecs, name, cookie.Value, store.Options.MaxAge)
+	if err != nil {
+		return s, nil
+	}
+	var stored struct {
+		ID     string                 `json:"id"`
+		Values map[string]interface{} `json:"values"`
+	}
+	if err := json.Unmarshal(data, &stored); err != nil || stored.ID == "" {
+		return s, nil
+	}
+	s.ID, s.IsNew = stored.ID, false
+	if stored.Values != nil {
+		s.Values = stored.Values
+	}
+	return s, nil
+}
+
+// Save implement SessionStore
+func (store *CookieStore) Save(rw http.ResponseWriter, s *Session) error {
+	if s.destroyed {
+		http.SetCookie(rw, store.Options.cookie(s, ""))
+		return nil
+	}
+	data, err := json.Marshal(map[string]interface{}{"id": s.ID, "values": s.Values})
+	if err != nil {
+		return err
+	}
+	value, err := store.codecs[0].encode(s.Name, data)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(rw, store.Options.cookie(s, value))
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Memory store
+
+// NewMemorySessionStore create a MemorySessionStore, keyPairs sign session id cookies, see NewCookieStore
+func NewMemorySessionStore(keyPairs ...[]byte) *MemorySessionStore {
+	return &MemorySessionStore{
+		Options:  defaultSessionOptions(),
+		codecs:   newCookieCodecs(keyPairs),
+		sessions: make(map[string]memorySession),
+	}
+}
+
+// Load implement SessionStore
+func (store *MemorySessionStore) Load(r *http.Request, name string) (*Session, error) {
+	s := newSession(name)
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	id, err := decodeCookie(store.codecs, name, cookie.Value, store.Options.MaxAge)
+	if err != nil {
+		return s, nil
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	stored, ok := store.sessions[string(id)]
+	if !ok {
+		return s, nil
+	}
+	if time.Now().After(stored.expire) {
+		delete(store.sessions, string(id))
+		return s, nil
+	}
+	s.ID, s.IsNew = string(id), false
+	for k, v := range stored.values {
+		s.Values[k] = v
+	}
+	return s, nil
+}
+
+// Save implement SessionStore
+func (store *MemorySessionStore) Save(rw http.ResponseWriter, s *Session) error {
+	store.mu.Lock()
+	if s.previousID != "" {
+		delete(store.sessions, s.previousID)
+	}
+	if s.destroyed {
+		delete(store.sessions, s.ID)
+		store.mu.Unlock()
+		http.SetCookie(rw, store.Options.cookie(s, ""))
+		return nil
+	}
+	values := make(map[string]interface{}, len(s.Values))
+	for k, v := range s.Values {
+		values[k] = v
+	}
+	maxAge := store.Options.MaxAge
+	if maxAge <= 0 {
+		maxAge = defaultSessionMaxAge
+	}
+	store.sessions[s.ID] = memorySession{values: values, expire: time.Now().Add(time.Duration(maxAge) * time.Second)}
+	store.mu.Unlock()
+
+	value, err := store.codecs[0].encode(s.Name, []byte(s.ID))
+	if err != nil {
+		return err
+	}
+	http.SetCookie(rw, store.Options.cookie(s, value))
+	return nil
+}
+
+// Cleanup delete expired sessions, it should be called periodically
+func (store *MemorySessionStore) Cleanup() {
+	now := time.Now()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for id, s := range store.sessions {
+		if now.After(s.expire) {
+			delete(store.sessions, id)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Signed cookies
+
+func newCookieCodecs(keyPairs [][]byte) []cookieCodec {
+	assert(len(keyPairs) > 0 && len(keyPairs[0]) > 0, "cookie hash key cannot be empty")
+	codecs := make([]cookieCodec, 0, (len(keyPairs)+1)/2)
+	for i := 0; i < len(keyPairs); i += 2 {
+		codec := cookieCodec{hashKey: keyPairs[i]}
+		if i+1 < len(keyPairs) && len(keyPairs[i+1]) > 0 {
+			block, err := aes.NewCipher(keyPairs[i+1])
+			assert(err == nil, "invalid cookie block key, it must be 16, 24 or 32 bytes long")
+			codec.block, _ = cipher.NewGCM(block)
+		}
+		codecs = append(codecs, codec)
+	}
+	return codecs
+}
+
+// encode data of cookie named name into "base64(timestamp|data).base64(mac)"
+func (codec cookieCodec) encode(name string, data []byte) (string, error) {
+	if codec.block != nil {
+		nonce := make([]byte, codec.block.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		data = codec.block.Seal(nonce, nonce, data, []byte(name))
+	}
+	payload := make([]byte, 8+len(data))
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+	copy(payload[8:], data)
+
+	value := base64.RawURLEncoding.EncodeToString(payload)
+	return value + "." + base64.RawURLEncoding.EncodeToString(codec.mac(name, value)), nil
+}
+
+// decode value of cookie named name, maxAge in seconds is checked if positive
+func (codec cookieCodec) decode(name, value string, maxAge int) ([]byte, error) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil, ErrInvalidCookie
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil || !hmac.Equal(mac, codec.mac(name, value[:i])) {
+		return nil, ErrInvalidCookie
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil || len(payload) < 8 {
+		return nil, ErrInvalidCookie
+	}
+	created := int64(binary.BigEndian.Uint64(payload))
+	if maxAge > 0 && time.Now().Unix()-created > int64(maxAge) {
+		return nil, ErrCookieExpired
+	}
+
+	data := payload[8:]
+	if codec.block != nil {
+		size := codec.block.NonceSize()
+		if len(data) < size {
+			return nil, ErrInvalidCookie
+		}
+		if data, err = codec.block.Open(nil, data[:size], data[size:], []byte(name)); err != nil {
+			return nil, ErrInvalidCookie
+		}
+	}
+	return data, nil
+}
+
+func (codec cookieCodec) mac(name, value string) []byte {
+	h := hmac.New(sha256.New, codec.hashKey)
+	io.WriteString(h, name)
+	h.Write([]byte{'|'})
+	io.WriteString(h, value)
+	return h.Sum(nil)
+}
+
+// decodeCookie try every codec in order
+func decodeCookie(codecs []cookieCodec, name, value string, maxAge int) ([]byte, error) {
+	err := ErrInvalidCookie
+	for _, codec := range codecs {
+		var data []byte
+		if data, err = codec.decode(name, value, maxAge); err == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
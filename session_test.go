@@ -0,0 +1,113 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessions(t *testing.T) {
+	hashKey := []byte("hash-key")
+	blockKey := []byte("0123456789abcdef")
+	tests := []struct {
+		name  string
+		store SessionStore
+	}{
+		{"signed cookie", NewCookieStore(hashKey)},
+		{"encrypted cookie", NewCookieStore(hashKey, blockKey)},
+		{"memory", NewMemorySessionStore(hashKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Filter(Sessions("session", tt.store))
+			s.Get("/login", func(c *Context) {
+				session, _ := c.Session()
+				c.RegenerateSession()
+				session.Values["user"] = "zen"
+				if err := c.SaveSession(); err != nil {
+					t.Errorf("SaveSession() error = %v", err)
+				}
+				c.RawStr(session.ID)
+			})
+			s.Get("/me", func(c *Context) {
+				session, _ := c.Session()
+				user, _ := session.Values["user"].(string)
+				c.RawStr(user)
+			})
+			s.Get("/logout", func(c *Context) {
+				session, _ := c.Session()
+				session.Destroy()
+				c.SaveSession()
+				c.RawStr("bye")
+			})
+
+			serve := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(GET, path, nil)
+				if cookie != nil {
+					req.AddCookie(cookie)
+				}
+				rw := httptest.NewRecorder()
+				s.ServeHTTP(rw, req)
+				return rw
+			}
+			cookieOf := func(rw *httptest.ResponseRecorder) *http.Cookie {
+				for _, cookie := range (&http.Response{Header: rw.Header()}).Cookies() {
+					if cookie.Name == "session" {
+						return cookie
+					}
+				}
+				t.Fatal("no session cookie")
+				return nil
+			}
+
+			login := serve("/login", nil)
+			cookie := cookieOf(login)
+			if got := serve("/me", cookie).Body.String(); got != "zen" {
+				t.Errorf("user = %q, want zen", got)
+			}
+
+			// a session fixed by attacker gets a new id on login
+			relogin := serve("/login", cookie)
+			if relogin.Body.String() == login.Body.String() {
+				t.Errorf("session id is not regenerated")
+			}
+
+			tampered := *cookie
+			tampered.Value = "x" + tampered.Value[1:]
+			if got := serve("/me", &tampered).Body.String(); got != "" {
+				t.Errorf("tampered user = %q, want empty", got)
+			}
+
+			if expired := cookieOf(serve("/logout", cookie)); expired.MaxAge >= 0 {
+				t.Errorf("logout cookie MaxAge = %d, want negative", expired.MaxAge)
+			}
+		})
+	}
+}
+
+func TestCookieStore_rotation(t *testing.T) {
+	oldStore := NewCookieStore([]byte("old"))
+	newStore := NewCookieStore([]byte("new"), nil, []byte("old"), nil)
+
+	session := newSession("session")
+	session.Values["user"] = "zen"
+	rw := httptest.NewRecorder()
+	oldStore.Save(rw, session)
+
+	req := httptest.NewRequest(GET, "/", nil)
+	req.Header.Set("Cookie", rw.Header().Get("Set-Cookie"))
+	loaded, _ := newStore.Load(req, "session")
+	if loaded.IsNew || loaded.Values["user"] != "zen" {
+		t.Errorf("Load() with rotated keys = %+v, want values of old cookie", loaded)
+	}
+}
+
+func TestContext_Session_noStore(t *testing.T) {
+	s := New()
+	c := s.getContext(httptest.NewRecorder(), httptest.NewRequest(GET, "/", nil))
+	defer s.putBackContext(c)
+	if _, err := c.Session(); err != ErrNoSessionStore {
+		t.Errorf("Session() error = %v, want %v", err, ErrNoSessionStore)
+	}
+}
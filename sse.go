@@ -0,0 +1,197 @@
+package zen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	textEventStream = "text/event-stream"
+	lastEventID     = "Last-Event-ID"
+)
+
+var (
+	// ErrFlushNotSupported is returned by SSE, Flush and streamed responses when response
+	// can not be flushed to client, such as in a handler wrapped by Timeout
+	ErrFlushNotSupported = errors.New("zen: response writer does not support flushing")
+	// ErrEventStreamClosed is returned when sending into an event stream after its request is handled
+	ErrEventStreamClosed = errors.New("zen: event stream closed")
+	// ErrInvalidEventField is returned when event name or id contains a line break
+	ErrInvalidEventField = errors.New("zen: event name and id cannot contain line breaks")
+)
+
+// EventStream write server-sent events to client, it is safe for concurrent use
+type EventStream struct {
+	ctx         context.Context
+	lastEventID string
+	mu          sync.Mutex
+	w           io.Writer
+	flusher     http.Flusher
+	closed      bool
+	stop        chan struct{}
+}
+
+// SSE start a text/event-stream response. Sends fail once client disconnects,
+// see EventStream.Done, and the stream is closed after the handler returns.
+func (c *Context) SSE() (*EventStream, error) {
+	flusher, ok := c.rw.flusher()
+	if !ok {
+		return nil, ErrFlushNotSupported
+	}
+
+	header := c.rw.Header()
+	header.Set(contentType, textEventStream)
+	header.Set("Cache-Control", "no-cache")
+	// disable response buffering of nginx
+	header.Set("X-Accel-Buffering", "no")
+	c.WriteStatus(http.StatusOK)
+	flusher.Flush()
+
+	// events bypass c.rw, which is already written, as heartbeat sends concurrently
+	c.eventStream = &EventStream{
+		ctx:         c.Req.Context(),
+		lastEventID: c.Req.Header.Get(lastEventID),
+		w:           c.rw.writer,
+		flusher:     flusher,
+	}
+	return c.eventStream, nil
+}
+
+// LastEventID return the id of the last event received by client before reconnecting,
+// resume the stream after it
+func (s *EventStream) LastEventID() string {
+	return s.lastEventID
+}
+
+// Done return a channel which is closed when client disconnects
+func (s *EventStream) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// Send send an event to client, event and id are omitted if empty. Data of string and
+// []byte is sent as is, other types are encoded as json, multi-line data is split into
+// several data fields on any of \r\n, \r and \n.
+func (s *EventStream) Send(event, id string, data interface{}) error {
+	if strings.ContainsAny(event, "\r\n") || strings.ContainsAny(id, "\r\n") {
+		return ErrInvalidEventField
+	}
+
+	var payload []byte
+	switch d := data.(type) {
+	case string:
+		payload = []byte(d)
+	case []byte:
+		payload = d
+	default:
+		var err error
+		if payload, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	if event != "" {
+		buf.WriteString("event: " + event + "\n")
+	}
+	if id != "" {
+		buf.WriteString("id: " + id + "\n")
+	}
+	for _, line := range splitLines(string(payload)) {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Retry tell client to wait d before reconnecting
+func (s *EventStream) Retry(d time.Duration) error {
+	return s.write([]byte("retry: " + strconv.FormatInt(int64(d/time.Millisecond), 10) + "\n\n"))
+}
+
+// Comment send a comment, which is ignored by client
+func (s *EventStream) Comment(text string) error {
+	var buf bytes.Buffer
+	for _, line := range splitLines(text) {
+		buf.WriteString(":" + line + "\n")
+	}
+	buf.WriteByte('\n')
+	return s.write(buf.Bytes())
+}
+
+// Heartbeat send an empty comment every interval until client disconnects or stream is closed,
+// which keeps proxies from closing an idle connection. It replaces the previous heartbeat.
+func (s *EventStream) Heartbeat(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.stop != nil {
+		close(s.stop)
+	}
+	stop := make(chan struct{})
+	s.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Comment(""); err != nil {
+					return
+				}
+			case <-stop:
+				return
+			case <-s.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close stop heartbeat, later sends fail with ErrEventStreamClosed
+func (s *EventStream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+}
+
+// write write p to client and flush it
+func (s *EventStream) write(p []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrEventStreamClosed
+	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(p); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// splitLines split s on \r\n, \r and \n, which all end a line of event stream
+func splitLines(s string) []string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	s = strings.Replace(s, "\r", "\n", -1)
+	return strings.Split(s, "\n")
+}
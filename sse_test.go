@@ -0,0 +1,104 @@
+package zen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// plainWriter hide the Flush of underlying writer
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestContext_SSE(t *testing.T) {
+	s := New()
+	var stream *EventStream
+	var errs []error
+	var lastID string
+	s.Get("/", func(c *Context) {
+		var err error
+		if stream, err = c.SSE(); err != nil {
+			t.Fatal(err)
+		}
+		lastID = stream.LastEventID()
+		errs = append(errs,
+			stream.Retry(3*time.Second),
+			stream.Send("", "", "hello"),
+			stream.Send("update", "8", map[string]int{"cpu": 42}),
+			stream.Send("multi", "", "a\r\nb\rid: 9\nc"),
+			stream.Comment("note\revent: x"),
+			stream.Send("bad\nevent", "", "x"),
+		)
+		stream.Heartbeat(time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+	})
+	req := httptest.NewRequest(GET, "/", nil)
+	req.Header.Set(lastEventID, "7")
+	rw := httptest.NewRecorder()
+	s.ServeHTTP(rw, req)
+
+	for i, want := range []error{nil, nil, nil, nil, nil, ErrInvalidEventField} {
+		if errs[i] != want {
+			t.Errorf("error %d = %v, want %v", i, errs[i], want)
+		}
+	}
+	if lastID != "7" {
+		t.Errorf("LastEventID() = %q", lastID)
+	}
+	if ct := rw.Header().Get(contentType); ct != textEventStream {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !rw.Flushed {
+		t.Error("response is not flushed")
+	}
+
+	want := "retry: 3000\n\n" +
+		"data: hello\n\n" +
+		"event: update\nid: 8\ndata: {\"cpu\":42}\n\n" +
+		"event: multi\ndata: a\ndata: b\ndata: id: 9\ndata: c\n\n" +
+		":note\n:event: x\n\n"
+	body := rw.Body.String()
+	if !strings.HasPrefix(body, want) {
+		t.Errorf("body = %q, want prefix %q", body, want)
+	}
+	if !strings.Contains(body[len(want):], ":\n\n") {
+		t.Errorf("no heartbeat in %q", body[len(want):])
+	}
+
+	// stream is closed once the handler returns
+	if err := stream.Send("late", "", "x"); err != ErrEventStreamClosed {
+		t.Errorf("Send() after handler returns error = %v", err)
+	}
+}
+
+func TestContext_SSE_Disconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New()
+	var err error
+	s.Get("/", func(c *Context) {
+		stream, _ := c.SSE()
+		cancel()
+		<-stream.Done()
+		err = stream.Send("", "", "x")
+	})
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/", nil).WithContext(ctx))
+	if err != context.Canceled {
+		t.Errorf("Send() after disconnect error = %v", err)
+	}
+}
+
+func TestContext_SSE_NotFlusher(t *testing.T) {
+	s := New()
+	var err error
+	s.Get("/", func(c *Context) {
+		_, err = c.SSE()
+	})
+	s.ServeHTTP(plainWriter{httptest.NewRecorder()}, httptest.NewRequest(GET, "/", nil))
+	if err != ErrFlushNotSupported {
+		t.Errorf("SSE() error = %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package zen
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	applicationNDJSON = "application/x-ndjson"
+
+	// defaultStreamFlushInterval is the default interval of flushing streamed responses
+	defaultStreamFlushInterval = time.Second
+)
+
+// Iterator return the next value to stream, and io.EOF when there are no more values
+type Iterator func() (interface{}, error)
+
+// SetStreamFlushInterval set the interval of flushing StreamJSONArray and NDJSON
+// responses to client, a non-positive d flushes after every value
+func (s *Server) SetStreamFlushInterval(d time.Duration) {
+	s.streamFlushInterval = d
+}
+
+// ResponseWriter return the response writer of request. It always implements http.Flusher,
+// http.Hijacker and http.Pusher, but only does so effectively when the underlying writer does:
+// Flush is a no-op otherwise, such as in a handler wrapped by Timeout, Hijack returns
+// ErrHijackNotSupported and Push returns http.ErrNotSupported. Use Context.Flush to tell.
+func (c *Context) ResponseWriter() http.ResponseWriter {
+	return c.rw
+}
+
+// Flush send buffered response to client, ErrFlushNotSupported is returned if response
+// can not be flushed, such as in a handler wrapped by Timeout
+func (c *Context) Flush() error {
+	if _, ok := c.rw.flusher(); !ok {
+		return ErrFlushNotSupported
+	}
+	c.rw.Flush()
+	return nil
+}
+
+// StreamJSONArray write values returned by next as a json array with status code,
+// without holding all of them in memory. Streaming stops when client disconnects,
+// an error after the first value truncates the array, which client sees as invalid json.
+// ErrFlushNotSupported is returned before anything is written if response can not be flushed.
+func (c *Context) StreamJSONArray(code int, next Iterator) error {
+	if _, ok := c.rw.flusher(); !ok {
+		return ErrFlushNotSupported
+	}
+	c.WriteHeader(contentType, applicationJSON)
+	c.WriteStatus(code)
+	if _, err := io.WriteString(c.rw, "["); err != nil {
+		return err
+	}
+
+	first := true
+	err := c.stream(next, func(v interface{}) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(c.rw, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = c.rw.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(c.rw, "]")
+	c.rw.Flush()
+	return err
+}
+
+// NDJSON write values returned by next as newline delimited json with status code,
+// one value per line. Streaming stops when client disconnects.
+// ErrFlushNotSupported is returned before anything is written if response can not be flushed.
+func (c *Context) NDJSON(code int, next Iterator) error {
+	if _, ok := c.rw.flusher(); !ok {
+		return ErrFlushNotSupported
+	}
+	c.WriteHeader(contentType, applicationNDJSON)
+	c.WriteStatus(code)
+
+	enc := json.NewEncoder(c.rw)
+	err := c.stream(next, func(v interface{}) error {
+		return enc.Encode(v)
+	})
+	c.rw.Flush()
+	return err
+}
+
+// stream write values returned by next with write, and flush response at most interval
+// after a value is written, even if next blocks meanwhile
+func (c *Context) stream(next Iterator, write func(v interface{}) error) error {
+	done := c.Req.Context().Done()
+	interval := c.server.streamFlushInterval
+	flusher, _ := c.rw.flusher()
+
+	// timer flushes in its own goroutine, so writes and flushes are serialized by mu
+	var (
+		mu      sync.Mutex
+		timer   *time.Timer
+		stopped bool
+	)
+	defer func() {
+		mu.Lock()
+		stopped = true
+		if timer != nil {
+			timer.Stop()
+		}
+		mu.Unlock()
+	}()
+	flush := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !stopped {
+			flusher.Flush()
+			timer = nil
+		}
+	}
+
+	for {
+		select {
+		case <-done:
+			return c.Req.Context().Err()
+		default:
+		}
+
+		v, err := next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		err = write(v)
+		if err == nil {
+			if interval <= 0 {
+				flusher.Flush()
+			} else if timer == nil {
+				timer = time.AfterFunc(interval, flush)
+			}
+		}
+		mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
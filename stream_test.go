@@ -0,0 +1,192 @@
+package zen
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sliceIterator iterate values, then return err
+func sliceIterator(values []interface{}, err error) Iterator {
+	i := 0
+	return func() (interface{}, error) {
+		if i == len(values) {
+			return nil, err
+		}
+		i++
+		return values[i-1], nil
+	}
+}
+
+// flushRecorder count flushes and record body flushed so far
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	mu          sync.Mutex
+	flushes     int
+	flushedBody string
+}
+
+func (r *flushRecorder) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushes++
+	r.flushedBody = r.Body.String()
+	r.ResponseRecorder.Flush()
+}
+
+func TestContext_StreamJSONArray(t *testing.T) {
+	errIterate := errors.New("iterate")
+	tests := []struct {
+		name    string
+		values  []interface{}
+		iterErr error
+		want    string
+		wantErr error
+	}{
+		{"empty", nil, io.EOF, `[]`, nil},
+		{"values", []interface{}{1, "a", map[string]int{"b": 2}}, io.EOF, `[1,"a",{"b":2}]`, nil},
+		{"error", []interface{}{1}, errIterate, `[1`, errIterate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			var err error
+			s.Get("/", func(c *Context) {
+				err = c.StreamJSONArray(http.StatusOK, sliceIterator(tt.values, tt.iterErr))
+			})
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, httptest.NewRequest(GET, "/", nil))
+
+			if err != tt.wantErr {
+				t.Errorf("StreamJSONArray() error = %v, want %v", err, tt.wantErr)
+			}
+			if rw.Body.String() != tt.want {
+				t.Errorf("body = %s, want %s", rw.Body, tt.want)
+			}
+			if ct := rw.Header().Get(contentType); ct != applicationJSON {
+				t.Errorf("Content-Type = %q", ct)
+			}
+		})
+	}
+}
+
+func TestContext_NDJSON(t *testing.T) {
+	s := New()
+	s.SetStreamFlushInterval(0)
+	s.Get("/", func(c *Context) {
+		c.NDJSON(http.StatusOK, sliceIterator([]interface{}{1, "a", map[string]int{"b": 2}}, io.EOF))
+	})
+	rw := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	s.ServeHTTP(rw, httptest.NewRequest(GET, "/", nil))
+
+	if want := "1\n\"a\"\n{\"b\":2}\n"; rw.Body.String() != want {
+		t.Errorf("body = %q, want %q", rw.Body, want)
+	}
+	if ct := rw.Header().Get(contentType); ct != applicationNDJSON {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	// every value and the end of stream
+	if rw.flushes != 4 {
+		t.Errorf("flushes = %d, want 4", rw.flushes)
+	}
+}
+
+func TestContext_Stream_Disconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := New()
+	var err error
+	calls := 0
+	s.Get("/", func(c *Context) {
+		err = c.NDJSON(http.StatusOK, func() (interface{}, error) {
+			calls++
+			if calls == 2 {
+				cancel()
+			}
+			return calls, nil
+		})
+	})
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/", nil).WithContext(ctx))
+
+	if err != context.Canceled || calls != 2 {
+		t.Errorf("NDJSON() error = %v after %d values", err, calls)
+	}
+}
+
+func TestResponseWriter_Interfaces(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &responseWriter{writer: rec}
+	var _ http.Flusher = w
+	var _ http.Hijacker = w
+	var _ http.Pusher = w
+
+	if _, _, err := w.Hijack(); err != ErrHijackNotSupported {
+		t.Errorf("Hijack() error = %v", err)
+	}
+	if err := w.Push("/app.js", nil); err != http.ErrNotSupported {
+		t.Errorf("Push() error = %v", err)
+	}
+	w.Flush()
+	if !rec.Flushed || !w.written {
+		t.Error("Flush() is not passed to underlying writer")
+	}
+}
+
+func TestContext_Stream_FlushInterval(t *testing.T) {
+	s := New()
+	s.SetStreamFlushInterval(5 * time.Millisecond)
+	var flushed string
+	s.Get("/", func(c *Context) {
+		rw := c.rw.writer.(*flushRecorder)
+		calls := 0
+		c.NDJSON(http.StatusOK, func() (interface{}, error) {
+			calls++
+			switch calls {
+			case 1:
+				return 1, nil
+			case 2:
+				// a slow iterator does not hold back the written value
+				time.Sleep(50 * time.Millisecond)
+				rw.mu.Lock()
+				flushed = rw.flushedBody
+				rw.mu.Unlock()
+				return 2, nil
+			}
+			return nil, io.EOF
+		})
+	})
+	rw := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	s.ServeHTTP(rw, httptest.NewRequest(GET, "/", nil))
+
+	if flushed != "1\n" {
+		t.Errorf("flushed before next value = %q, want %q", flushed, "1\n")
+	}
+	if want := "1\n2\n"; rw.Body.String() != want {
+		t.Errorf("body = %q, want %q", rw.Body, want)
+	}
+}
+
+func TestContext_Flush(t *testing.T) {
+	var errs []error
+	s := New()
+	s.Get("/", func(c *Context) {
+		errs = append(errs, c.Flush())
+	})
+	s.Get("/timeout", Timeout(time.Second, func(c *Context) {
+		errs = append(errs, c.Flush())
+	}))
+	rw := httptest.NewRecorder()
+	s.ServeHTTP(rw, httptest.NewRequest(GET, "/", nil))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(GET, "/timeout", nil))
+
+	if errs[0] != nil || !rw.Flushed {
+		t.Errorf("Flush() error = %v, flushed = %v", errs[0], rw.Flushed)
+	}
+	if errs[1] != ErrFlushNotSupported {
+		t.Errorf("Flush() under Timeout error = %v, want ErrFlushNotSupported", errs[1])
+	}
+}
@@ -0,0 +1,198 @@
+package zen
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SetTimeout set a server wide deadline for filters and handlers of every request,
+// a non-positive d disables it
+func (s *Server) SetTimeout(d time.Duration) {
+	s.timeout = d
+}
+
+// HandleTimeout set server's timeoutHandler, which is called when
+// a handler overruns its deadline
+func (s *Server) HandleTimeout(handler HandlerFunc) {
+	s.timeoutHandler = handler
+}
+
+// Timeout wrap handler with a route specific deadline, see Server.SetTimeout
+func Timeout(d time.Duration, handler HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		c.server.runWithTimeout(c, d, handler)
+	}
+}
+
+// runWithTimeout run fn with a copy of c, whose request carries a deadline of d
+// and whose response is buffered. The buffer and the state of the copy are put back
+// into c if fn returns in time, otherwise server's timeoutHandler responds and later
+// writes of fn are discarded, so fn never touches c after it is put back into contextPool.
+func (s *Server) runWithTimeout(c *Context, d time.Duration, fn HandlerFunc) {
+	ctx, cancel := context.WithTimeout(c.Req.Context(), d)
+	defer cancel()
+
+	tw := &timeoutWriter{header: make(http.Header)}
+	inner := c.timeoutCopy(c.Req.WithContext(ctx), tw)
+
+	done := make(chan struct{})
+	panicChan := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			err := recover()
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+			if tw.timedOut {
+				// c may have been reused, so the copy removes its own uploads
+				inner.removeUploads()
+				return
+			}
+			if err != nil {
+				panicChan <- err
+				return
+			}
+			close(done)
+		}()
+		fn(inner)
+	}()
+
+	select {
+	case err := <-panicChan:
+		c.restore(inner)
+		// re-panic in request's goroutine so that server's panicHandler can handle it
+		panic(err)
+
+	case <-done:
+		c.restore(inner)
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		if !inner.rw.written {
+			return
+		}
+		header := c.rw.Header()
+		for k, v := range tw.header {
+			header[k] = v
+		}
+		if tw.code == 0 {
+			tw.code = http.StatusOK
+		}
+		c.rw.WriteHeader(tw.code)
+		c.rw.Write(tw.buf.Bytes())
+
+	case <-ctx.Done():
+		tw.mu.Lock()
+		tw.timedOut = true
+		tw.mu.Unlock()
+		// fn may have returned right before timeout, its uploads are removed with c then
+		select {
+		case <-done:
+			c.restore(inner)
+		case <-panicChan:
+			c.restore(inner)
+		default:
+		}
+		s.handleTimeout(c)
+	}
+}
+
+// timeoutCopy return a copy of c with req and response writer w, which shares
+// no slice with c, so that c can be reused while the copy is still in use
+func (c *Context) timeoutCopy(req *http.Request, w http.ResponseWriter) *Context {
+	return &Context{
+		Req:           req,
+		rw:            &responseWriter{writer: w},
+		params:        append(Params(nil), c.params...),
+		parsed:        c.parsed,
+		server:        c.server,
+		bodyPrepared:  c.bodyPrepared,
+		bodyErr:       c.bodyErr,
+		bodyLimitErr:  c.bodyLimitErr,
+		maxBodyBytes:  c.maxBodyBytes,
+		principal:     c.principal,
+		jwtClaims:     c.jwtClaims,
+		sessionName:   c.sessionName,
+		sessionStore:  c.sessionStore,
+		session:       c.session,
+		csrfToken:     c.csrfToken,
+		cspNonce:      c.cspNonce,
+		locale:        c.locale,
+		query:         c.query,
+		proxyResolved: c.proxyResolved,
+		clientIP:      c.clientIP,
+		scheme:        c.scheme,
+		host:          c.host,
+	}
+}
+
+// restore put the state of inner, a timeoutCopy of c which is no longer in use, back into c.
+// c keeps its own request context.
+func (c *Context) restore(inner *Context) {
+	c.Req = inner.Req.WithContext(c.Req.Context())
+	c.parsed = inner.parsed
+	c.bodyPrepared = inner.bodyPrepared
+	c.bodyErr = inner.bodyErr
+	c.bodyLimitErr = inner.bodyLimitErr
+	c.maxBodyBytes = inner.maxBodyBytes
+	c.principal = inner.principal
+	c.jwtClaims = inner.jwtClaims
+	c.sessionName = inner.sessionName
+	c.sessionStore = inner.sessionStore
+	c.session = inner.session
+	c.csrfToken = inner.csrfToken
+	c.cspNonce = inner.cspNonce
+	c.locale = inner.locale
+	c.query = inner.query
+	c.tempFiles = append(c.tempFiles, inner.tempFiles...)
+	c.proxyResolved = inner.proxyResolved
+	c.clientIP = inner.clientIP
+	c.scheme = inner.scheme
+	c.host = inner.host
+}
+
+// handleTimeout call server's timeout handler
+func (s *Server) handleTimeout(c *Context) {
+	if s.timeoutHandler != nil {
+		s.timeoutHandler(c)
+		return
+	}
+
+	http.Error(c.rw, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// timeoutWriter buffer response until handler returns,
+// and reject any write after timeout
+type timeoutWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	buf      bytes.Buffer
+	code     int
+	timedOut bool
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(p)
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.code != 0 {
+		return
+	}
+	w.code = code
+}
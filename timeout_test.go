@@ -0,0 +1,124 @@
+package zen
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	tests := []struct {
+		name     string
+		handler  HandlerFunc
+		wantCode int
+		wantBody string
+	}{
+		{
+			"in time",
+			func(c *Context) {
+				c.WriteHeader("X-Zen", "1")
+				c.WriteStatus(http.StatusCreated)
+				c.RawStr("done")
+			},
+			http.StatusCreated,
+			"done",
+		},
+		{
+			"overrun",
+			func(c *Context) {
+				select {
+				case <-c.Req.Context().Done():
+				case <-release:
+				}
+				c.RawStr("late")
+			},
+			http.StatusServiceUnavailable,
+			"Service Unavailable\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			s.Get("/", Timeout(20*time.Millisecond, tt.handler))
+
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, httptest.NewRequest(GET, "/", nil))
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+			if rw.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", rw.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServer_SetTimeout(t *testing.T) {
+	s := New()
+	s.SetTimeout(10 * time.Millisecond)
+	s.HandleTimeout(func(c *Context) {
+		c.WriteStatus(http.StatusGatewayTimeout)
+	})
+	s.HandlePanic(func(c *Context, err interface{}) {
+		c.WriteStatus(http.StatusInternalServerError)
+	})
+	s.Get("/slow", func(c *Context) {
+		<-c.Req.Context().Done()
+	})
+	s.Get("/panic", func(c *Context) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/slow", http.StatusGatewayTimeout},
+		{"/panic", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, httptest.NewRequest(GET, tt.path, nil))
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestTimeout_Restore(t *testing.T) {
+	var locale, name string
+	var streamErr error
+	s := New()
+	s.Route(POST, "/", func(c *Context) {
+		Timeout(20*time.Millisecond, func(c *Context) {
+			c.SetLocale("fr")
+			c.Form("name")
+			streamErr = c.NDJSON(http.StatusOK, func() (interface{}, error) {
+				return nil, io.EOF
+			})
+		})(c)
+		locale, name = c.Locale(), c.Req.PostForm.Get("name")
+	})
+
+	req := httptest.NewRequest(POST, "/", strings.NewReader("name=zen"))
+	req.Header.Set(contentType, applicationForm)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	if locale != "fr" {
+		t.Errorf("Locale() = %q, want fr", locale)
+	}
+	if name != "zen" {
+		t.Errorf("PostForm name = %q, want zen", name)
+	}
+	if streamErr != ErrFlushNotSupported {
+		t.Errorf("NDJSON() error = %v, want ErrFlushNotSupported", streamErr)
+	}
+}
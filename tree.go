@@ -5,8 +5,8 @@ type Handlers []HandlerFunc
 
 // Param use to store url key value pair
 type Param struct {
-	key   string
-	value string
+	Key   string
+	Value string
 }
 
 // Params use to store url parameters
@@ -15,12 +15,18 @@ type Params []Param
 
 // Get params value by name
 func (p Params) Get(name string) string {
+	value, _ := p.lookup(name)
+	return value
+}
+
+// lookup return params value by name, ok is false if name is absent
+func (p Params) lookup(name string) (value string, ok bool) {
 	for _, v := range p {
-		if v.key == name {
-			return v.value
+		if v.Key == name {
+			return v.Value, true
 		}
 	}
-	return ""
+	return "", false
 }
 
 // countParams return parameter count in path
@@ -50,6 +56,7 @@ type node struct {
 	indices   []byte
 	prior     int
 	handlers  Handlers
+	fullPath  string
 	wild      bool
 	maxParams uint8
 }
@@ -97,6 +104,7 @@ func (n *node) addRoute(path string, handlers Handlers) {
 					indices:  n.indices,
 					children: n.children,
 					handlers: n.handlers,
+					fullPath: n.fullPath,
 					prior:    n.prior - 1,
 				}
 
@@ -109,6 +117,7 @@ func (n *node) addRoute(path string, handlers Handlers) {
 				n.indices = []byte{n.path[i]}
 				n.path = path[:i]
 				n.handlers = nil
+				n.fullPath = ""
 				n.wild = false
 			}
 
@@ -166,6 +175,7 @@ func (n *node) addRoute(path string, handlers Handlers) {
 					panic("duplicate handlers in '" + fullpath + "'")
 				}
 				n.handlers = handlers
+				n.fullPath = fullpath
 			}
 			return
 		}
@@ -264,6 +274,7 @@ func (n *node) addChild(numParams uint8, path string, fullPath string, handlers
 				ndType:    all,
 				maxParams: 1,
 				handlers:  handlers,
+				fullPath:  fullPath,
 				prior:     1,
 			}
 			n.children = []*node{child}
@@ -272,9 +283,11 @@ func (n *node) addChild(numParams uint8, path string, fullPath string, handlers
 	}
 	n.path = path[offset:]
 	n.handlers = handlers
+	n.fullPath = fullPath
 }
 
-func (n *node) get(path string, po Params) (handlers Handlers, p Params) {
+// get return handlers and params of path, and the pattern of its route
+func (n *node) get(path string, po Params) (handlers Handlers, p Params, fullPath string) {
 	p = po
 LOOP:
 	//outer loop
@@ -309,8 +322,8 @@ LOOP:
 					}
 					i := len(p)
 					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].key = n.path[1:]
-					p[i].value = path[:end]
+					p[i].Key = n.path[1:]
+					p[i].Value = path[:end]
 
 					// we need to go deeper!
 					if end < len(path) {
@@ -323,6 +336,7 @@ LOOP:
 					}
 
 					if handlers = n.handlers; handlers != nil {
+						fullPath = n.fullPath
 						return
 					} else if len(n.children) == 1 {
 						// No handle found. Check if a handle for this path + a
@@ -338,10 +352,11 @@ LOOP:
 					}
 					i := len(p)
 					p = p[:i+1] // expand slice within preallocated capacity
-					p[i].key = n.path[2:]
-					p[i].value = path
+					p[i].Key = n.path[2:]
+					p[i].Value = path
 
 					handlers = n.handlers
+					fullPath = n.fullPath
 					return
 
 				default:
@@ -350,6 +365,7 @@ LOOP:
 			}
 		} else if path == n.path {
 			if handlers = n.handlers; handlers != nil {
+				fullPath = n.fullPath
 				return
 			}
 		}
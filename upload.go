@@ -0,0 +1,220 @@
+package zen
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"os"
+)
+
+// sniffLen is the number of bytes http.DetectContentType considers
+const sniffLen = 512
+
+var (
+	// ErrFileTooLarge is returned when an uploaded file exceeds the max file size
+	ErrFileTooLarge = errors.New("zen: uploaded file too large")
+)
+
+type (
+	// MultipartReader stream parts of a multipart request body without buffering them
+	MultipartReader struct {
+		c           *Context
+		r           *multipart.Reader
+		maxFileSize int64
+	}
+
+	// Part is a part of multipart body, reading it fails with ErrFileTooLarge
+	// once it exceeds the max file size
+	Part struct {
+		*multipart.Part
+		// ContentType is sniffed from part's content, see http.DetectContentType
+		ContentType string
+
+		c      *Context
+		reader io.Reader
+		max    int64
+		n      int64
+	}
+)
+
+// SetMaxFileSize set the max size in bytes of each uploaded file,
+// a non-positive n disables the limit
+func (s *Server) SetMaxFileSize(n int64) {
+	s.maxFileSize = n
+}
+
+// FormFile return the first uploaded file of multipart form with name,
+// which is rejected with ErrFileTooLarge if it exceeds the max file size
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if !c.parsed {
+		if err := c.parseInput(); err != nil {
+			return nil, err
+		}
+	}
+	if c.Req.MultipartForm == nil || len(c.Req.MultipartForm.File[name]) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	fh := c.Req.MultipartForm.File[name][0]
+	if max := c.server.maxFileSize; max > 0 && fh.Size > max {
+		return nil, ErrFileTooLarge
+	}
+	return fh, nil
+}
+
+// SaveUploadedFile save uploaded file into dst, which is created or truncated
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return saveFile(src, dst)
+}
+
+// SniffFileType detect the content type of uploaded file from its first 512 bytes,
+// the Content-Type sent by client is not trusted
+func SniffFileType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// MultipartReader return a reader streaming parts of request's multipart body,
+// it can not be used together with Form, FormFile or binding of the same request
+func (c *Context) MultipartReader() (*MultipartReader, error) {
+	if err := c.prepareBody(); err != nil {
+		return nil, err
+	}
+	r, err := c.Req.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	return &MultipartReader{c: c, r: r, maxFileSize: c.server.maxFileSize}, nil
+}
+
+// SetMaxFileSize override server's max file size for parts read from r
+func (r *MultipartReader) SetMaxFileSize(n int64) {
+	r.maxFileSize = n
+}
+
+// NextPart return the next part of body, or io.EOF if there are no more parts
+func (r *MultipartReader) NextPart() (*Part, error) {
+	p, err := r.r.NextPart()
+	if err != nil {
+		return nil, r.c.bodyError(err)
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(p, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, r.c.bodyError(err)
+	}
+	head = head[:n]
+	return &Part{
+		Part:        p,
+		ContentType: http.DetectContentType(head),
+		c:           r.c,
+		reader:      io.MultiReader(bytes.NewReader(head), p),
+		max:         r.maxFileSize,
+	}, nil
+}
+
+// Each call fn with every remaining part, until fn returns an error
+func (r *MultipartReader) Each(fn func(p *Part) error) error {
+	for {
+		p, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+}
+
+// Read implement io.Reader
+func (p *Part) Read(b []byte) (int, error) {
+	if p.max > 0 && p.n > p.max {
+		return 0, ErrFileTooLarge
+	}
+	// read one byte more than max to tell if part exceeds it
+	if p.max > 0 && int64(len(b)) > p.max-p.n+1 {
+		b = b[:p.max-p.n+1]
+	}
+	n, err := p.reader.Read(b)
+	p.n += int64(n)
+	if p.max > 0 && p.n > p.max {
+		return n, ErrFileTooLarge
+	}
+	if err != nil && err != io.EOF {
+		err = p.c.bodyError(err)
+	}
+	return n, err
+}
+
+// Save write part's content into dst, which is created or truncated, dst is removed on error
+func (p *Part) Save(dst string) error {
+	return saveFile(p, dst)
+}
+
+// SaveTemp write part's content into a temporary file and return its path,
+// the file is removed after the request is handled
+func (p *Part) SaveTemp() (string, error) {
+	f, err := ioutil.TempFile("", "zen-upload-")
+	if err != nil {
+		return "", err
+	}
+	p.c.tempFiles = append(p.c.tempFiles, f.Name())
+
+	_, err = io.Copy(f, p)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// saveFile copy src into file dst, dst is removed on error
+func saveFile(src io.Reader, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// removeUploads remove temporary files of request's multipart form and parts
+func (c *Context) removeUploads() {
+	if c.Req != nil && c.Req.MultipartForm != nil {
+		c.Req.MultipartForm.RemoveAll()
+	}
+	for _, name := range c.tempFiles {
+		os.Remove(name)
+	}
+	c.tempFiles = c.tempFiles[0:0]
+}
@@ -0,0 +1,221 @@
+package zen
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var pngHeader = []byte("\x89PNG\x0D\x0A\x1A\x0A")
+
+// newUploadRequest return a multipart request with a text field and files
+func newUploadRequest(t *testing.T, files map[string][]byte) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("title", "zen")
+	for name, content := range files {
+		fw, err := w.CreateFormFile(name, name+".bin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	w.Close()
+
+	req := httptest.NewRequest(POST, "/", &body)
+	req.Header.Set(contentType, w.FormDataContentType())
+	return req
+}
+
+func TestContext_FormFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "zen-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	big := bytes.Repeat([]byte("z"), 64<<10)
+	s := New()
+	s.SetMaxMultipartMemory(1 << 10)
+	s.SetMaxFileSize(32 << 10)
+
+	var form *multipart.Form
+	var errs []error
+	var fileType string
+	s.Route(POST, "/", func(c *Context) {
+		fh, err := c.FormFile("avatar")
+		errs = append(errs, err)
+		if err == nil {
+			errs = append(errs, c.SaveUploadedFile(fh, filepath.Join(dir, "avatar.png")))
+			fileType, _ = SniffFileType(fh)
+		}
+		_, err = c.FormFile("big")
+		errs = append(errs, err)
+		_, err = c.FormFile("missing")
+		errs = append(errs, err)
+		form = c.Req.MultipartForm
+	})
+	avatar := append(pngHeader, bytes.Repeat([]byte{0}, 2<<10)...)
+	s.ServeHTTP(httptest.NewRecorder(), newUploadRequest(t, map[string][]byte{"avatar": avatar, "big": big}))
+
+	want := []error{nil, nil, ErrFileTooLarge, http.ErrMissingFile}
+	for i := range want {
+		if errs[i] != want[i] {
+			t.Errorf("error %d = %v, want %v", i, errs[i], want[i])
+		}
+	}
+	if saved, _ := ioutil.ReadFile(filepath.Join(dir, "avatar.png")); !bytes.Equal(saved, avatar) {
+		t.Error("saved file mismatch")
+	}
+	if fileType != "image/png" {
+		t.Errorf("SniffFileType() = %q", fileType)
+	}
+
+	// files larger than max multipart memory are stored on disk until context is put back
+	for _, fhs := range form.File {
+		f, err := fhs[0].Open()
+		if err == nil {
+			f.Close()
+			if _, ok := f.(*os.File); ok {
+				t.Errorf("temporary file of %s is not removed", fhs[0].Filename)
+			}
+		}
+	}
+}
+
+func TestContext_MultipartReader(t *testing.T) {
+	s := New()
+	s.SetMaxFileSize(1 << 10)
+
+	type result struct {
+		name, contentType string
+		content           []byte
+		err               error
+	}
+	var results []result
+	var tempFile string
+	s.Route(POST, "/", func(c *Context) {
+		r, err := c.MultipartReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = r.Each(func(p *Part) error {
+			if p.FormName() == "avatar" {
+				name, err := p.SaveTemp()
+				tempFile = name
+				results = append(results, result{p.FormName(), p.ContentType, nil, err})
+				return err
+			}
+			content, err := ioutil.ReadAll(p)
+			results = append(results, result{p.FormName(), p.ContentType, content, err})
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		if _, err := os.Stat(tempFile); err != nil {
+			t.Errorf("temporary file is missing before request is handled: %v", err)
+		}
+	})
+	s.ServeHTTP(httptest.NewRecorder(), newUploadRequest(t, map[string][]byte{
+		"avatar": append(pngHeader, 1, 2, 3),
+	}))
+	if _, err := os.Stat(tempFile); !os.IsNotExist(err) {
+		t.Errorf("temporary file is not removed: %v", err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("parts = %+v", results)
+	}
+	if r := results[0]; r.name != "title" || string(r.content) != "zen" || !strings.HasPrefix(r.contentType, textPlain) || r.err != nil {
+		t.Errorf("title part = %+v", r)
+	}
+	if r := results[1]; r.name != "avatar" || r.contentType != "image/png" || r.err != nil {
+		t.Errorf("avatar part = %+v", r)
+	}
+}
+
+func TestPart_Read_MaxFileSize(t *testing.T) {
+	s := New()
+	var errs []error
+	s.Route(POST, "/", func(c *Context) {
+		r, _ := c.MultipartReader()
+		r.SetMaxFileSize(10)
+		r.Each(func(p *Part) error {
+			_, err := io.Copy(ioutil.Discard, p)
+			errs = append(errs, err)
+			return nil
+		})
+	})
+	s.ServeHTTP(httptest.NewRecorder(), newUploadRequest(t, map[string][]byte{
+		"big": bytes.Repeat([]byte("z"), 11),
+	}))
+	if len(errs) != 2 || errs[0] != nil || errs[1] != ErrFileTooLarge {
+		t.Errorf("errors = %v", errs)
+	}
+}
+
+func TestPart_SaveTemp_Timeout(t *testing.T) {
+	s := New()
+	s.SetTimeout(20 * time.Millisecond)
+	s.HandleTimeout(func(c *Context) {
+		c.WriteStatus(http.StatusGatewayTimeout)
+	})
+
+	saved := make(chan string, 1)
+	s.Route(POST, "/", func(c *Context) {
+		if c.Query("slow") != "" {
+			<-c.Req.Context().Done()
+		}
+		r, err := c.MultipartReader()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		r.Each(func(p *Part) error {
+			if p.FormName() == "avatar" {
+				name, _ := p.SaveTemp()
+				saved <- name
+			}
+			return nil
+		})
+		c.WriteStatus(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name     string
+		query    string
+		wantCode int
+	}{
+		{"in time", "", http.StatusNoContent},
+		{"timed out", "slow=1", http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newUploadRequest(t, map[string][]byte{"avatar": pngHeader})
+			req.URL.RawQuery = tt.query
+			rw := httptest.NewRecorder()
+			s.ServeHTTP(rw, req)
+			if rw.Code != tt.wantCode {
+				t.Errorf("code = %d, want %d", rw.Code, tt.wantCode)
+			}
+
+			name := <-saved
+			// a timed out handler removes its uploads once it returns
+			for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+				if _, err := os.Stat(name); os.IsNotExist(err) {
+					return
+				}
+			}
+			t.Errorf("temporary file %s is not removed", name)
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package zen
 
+import (
+	"crypto/rand"
+	"encoding/base64"
+)
+
 // assert c is true, else panic with msg
 func assert(c bool, msg string) {
 	if !c {
@@ -29,3 +34,12 @@ func longestCommonPrefixIndex(a, b string) int {
 	}
 	return i
 }
+
+// randomToken return a url safe base64 encoded string of n random bytes
+func randomToken(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic("zen: read random bytes: " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,523 @@
+package zen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	validTagName = "valid"
+	validMsgName = "msg"
+
+	applicationProblemJSON = "application/problem+json"
+)
+
+type (
+	// FieldError describe a field failing a validation rule
+	FieldError struct {
+		// Field is the path of struct field, such as Address.City
+		Field string `json:"field"`
+		// Key is the path of field in request, such as address.city
+		Key   string `json:"key"`
+		Rule  string `json:"rule"`
+		Param string `json:"param,omitempty"`
+		// Value is the rejected value, nil if field is a nil pointer
+		Value   interface{} `json:"value"`
+		Message string      `json:"message"`
+
+		// messageKey is the msg tag of field
+		messageKey string
+	}
+
+	// ValidationErrors is returned by binding and validating methods of Context
+	// when some fields fail their rules, it lists one error per failed field.
+	// A field failing to be bound from a form, query, params or headers fails
+	// rule type, whose param is the field's Go type.
+	ValidationErrors []*FieldError
+
+	// validationProblem is the body of Context.ValidationProblem, see RFC 7807
+	validationProblem struct {
+		Type   string           `json:"type"`
+		Title  string           `json:"title"`
+		Status int              `json:"status"`
+		Errors ValidationErrors `json:"errors"`
+	}
+
+	// ValidatorFunc report whether value satisfies a custom rule, param is the text
+	// following "=" in valid tag. value is dereferenced if field is a pointer.
+	ValidatorFunc func(value interface{}, param string) bool
+
+	// structRules is the compiled valid tags of a struct type
+	structRules struct {
+		fields []fieldRules
+		err    error
+	}
+
+	// fieldRules is the compiled valid tag of a field
+	fieldRules struct {
+		index     int
+		name      string
+		key       string
+		msg       string
+		rules     []validationRule
+		regexp    *regexp.Regexp
+		omitEmpty bool
+		nested    bool
+		embedded  bool
+	}
+
+	// validationRule is a rule of valid tag, such as min=3
+	validationRule struct {
+		name  string
+		param string
+		check ruleCheck
+	}
+
+	// ruleCheck report whether field of parent struct satisfies a rule
+	ruleCheck func(field, parent reflect.Value) bool
+)
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// has report whether e has an error of field
+func (e ValidationErrors) has(field string) bool {
+	for _, err := range e {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidationProblem respond errs as an application/problem+json document with status 422
+func (c *Context) ValidationProblem(errs ValidationErrors) error {
+	c.WriteHeader(contentType, applicationProblemJSON)
+	c.WriteStatus(http.StatusUnprocessableEntity)
+	return json.NewEncoder(c.rw).Encode(validationProblem{
+		Type:   "about:blank",
+		Title:  http.StatusText(http.StatusUnprocessableEntity),
+		Status: http.StatusUnprocessableEntity,
+		Errors: errs,
+	})
+}
+
+// RegisterValidator register fn as the rule of name in valid tags, which replaces
+// the builtin or custom rule of the same name
+func (s *Server) RegisterValidator(name string, fn ValidatorFunc) {
+	assert(fn != nil, "validator cannot be nil")
+	assert(name != "" && !strings.ContainsAny(name, ",="), "validator name cannot be empty or contain ',' and '='")
+
+	s.validationMu.Lock()
+	defer s.validationMu.Unlock()
+	s.validators[name] = fn
+	// rules compiled before are stale
+	s.validationRules = make(map[reflect.Type]*structRules)
+}
+
+// validate check input's fields against their valid tags, nested and embedded
+// structs are validated too. Fields failing their rules are reported together
+// as ValidationErrors.
+func (s *Server) validate(input interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(input))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	if err := s.validateStruct(v, "", "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStruct append errors of v's fields to errs, field names and keys of
+// nested structs are prefixed with their parent's
+func (s *Server) validateStruct(v reflect.Value, namePrefix, keyPrefix string, errs *ValidationErrors) error {
+	rules := s.structRules(v.Type())
+	if rules.err != nil {
+		return rules.err
+	}
+
+	for _, f := range rules.fields {
+		field := v.Field(f.index)
+		name, key := namePrefix+f.name, keyPrefix+f.key
+		if r, ok := f.validate(field, v); !ok {
+			*errs = append(*errs, f.fieldError(name, key, r, field))
+			continue
+		}
+		if !f.nested {
+			continue
+		}
+		if field = reflect.Indirect(field); !field.IsValid() {
+			continue
+		}
+		if f.embedded {
+			name, key = namePrefix, keyPrefix
+		} else {
+			name, key = name+".", key+"."
+		}
+		if err := s.validateStruct(field, name, key, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate check field of parent struct against rules, and return the first rule it fails
+func (f *fieldRules) validate(field, parent reflect.Value) (validationRule, bool) {
+	if f.regexp != nil {
+		if !f.regexp.MatchString(fieldString(reflect.Indirect(field))) {
+			return validationRule{name: "regexp", param: f.regexp.String()}, false
+		}
+		return validationRule{}, true
+	}
+
+	if f.omitEmpty && isEmpty(field) {
+		return validationRule{}, true
+	}
+	for _, r := range f.rules {
+		// a nil pointer only fails required
+		if r.name == "required" {
+			if isEmpty(field) {
+				return r, false
+			}
+			continue
+		}
+		value := reflect.Indirect(field)
+		if !value.IsValid() {
+			continue
+		}
+		if !r.check(value, parent) {
+			return r, false
+		}
+	}
+	return validationRule{}, true
+}
+
+// fieldError return the error of field failing rule r
+func (f *fieldRules) fieldError(name, key string, r validationRule, field reflect.Value) *FieldError {
+	err := &FieldError{Field: name, Key: key, Rule: r.name, Param: r.param}
+	if value := reflect.Indirect(field); value.IsValid() {
+		err.Value = value.Interface()
+	}
+	err.Message, err.messageKey = f.msg, f.msg
+	if err.Message == "" {
+		rule := r.name
+		if r.param != "" {
+			rule += "=" + r.param
+		}
+		err.Message = fmt.Sprintf("%s does not satisfy %s", name, rule)
+	}
+	return err
+}
+
+// structRules return the compiled rules of struct type t, rules are compiled once per type
+func (s *Server) structRules(t reflect.Type) *structRules {
+	s.validationMu.RLock()
+	rules, ok := s.validationRules[t]
+	s.validationMu.RUnlock()
+	if ok {
+		return rules
+	}
+
+	s.validationMu.Lock()
+	defer s.validationMu.Unlock()
+	if rules, ok = s.validationRules[t]; ok {
+		return rules
+	}
+	rules = s.compileStructRules(t)
+	s.validationRules[t] = rules
+	return rules
+}
+
+// compileStructRules compile valid tags of t's fields
+func (s *Server) compileStructRules(t reflect.Type) *structRules {
+	rules := &structRules{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		f := fieldRules{
+			index:    i,
+			name:     field.Name,
+			key:      fieldKey(field),
+			msg:      field.Tag.Get(validMsgName),
+			nested:   isNestedStruct(field.Type),
+			embedded: field.Anonymous,
+		}
+		if err := s.compileFieldRules(&f, field.Tag.Get(validTagName), t); err != nil {
+			rules.err = err
+			return rules
+		}
+		if f.rules != nil || f.regexp != nil || f.omitEmpty || f.nested {
+			rules.fields = append(rules.fields, f)
+		}
+	}
+	return rules
+}
+
+// fieldKey return the key of field in request, that is the name in its form, json
+// or xml tag, defaults to field's name
+func fieldKey(field reflect.StructField) string {
+	for _, tagName := range []string{inputTagName, "json", "xml"} {
+		name := strings.Split(field.Tag.Get(tagName), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// ruleListPattern match a tag of rules in the form of name[=param],...
+var ruleListPattern = regexp.MustCompile(`^\s*[A-Za-z_]\w*\s*(=[^,]*)?(,\s*[A-Za-z_]\w*\s*(=[^,]*)?)*$`)
+
+// compileFieldRules compile tag into f, a tag which is not a list of rules is a regex,
+// and an unknown rule in a list is an error, such as a typo of required,emial
+func (s *Server) compileFieldRules(f *fieldRules, tag string, t reflect.Type) error {
+	if tag == "" {
+		return nil
+	}
+	if !ruleListPattern.MatchString(tag) {
+		var err error
+		f.regexp, err = regexp.Compile(tag)
+		return err
+	}
+
+	for _, part := range strings.Split(tag, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		name, param := strings.TrimSpace(kv[0]), ""
+		if len(kv) == 2 {
+			param = strings.TrimSpace(kv[1])
+		}
+		if name == "omitempty" {
+			f.omitEmpty = true
+			continue
+		}
+		check, known, err := s.compileRule(name, param, t)
+		if !known {
+			return fmt.Errorf("zen: unknown validation rule %q of %s", name, t)
+		}
+		if err != nil {
+			return err
+		}
+		f.rules = append(f.rules, validationRule{name: name, param: param, check: check})
+	}
+	return nil
+}
+
+// compileRule compile rule name with param, known is false if no rule is named name
+func (s *Server) compileRule(name, param string, t reflect.Type) (check ruleCheck, known bool, err error) {
+	if fn, ok := s.validators[name]; ok {
+		return func(field, _ reflect.Value) bool {
+			return fn(field.Interface(), param)
+		}, true, nil
+	}
+
+	switch name {
+	case "required":
+		return nil, true, nil
+
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, true, fmt.Errorf("zen: invalid parameter of rule %s: %q", name, param)
+		}
+		return sizeRule(name, n), true, nil
+
+	case "email":
+		return func(field, _ reflect.Value) bool {
+			return isEmail(fieldString(field))
+		}, true, nil
+
+	case "oneof":
+		options := strings.Fields(param)
+		return func(field, _ reflect.Value) bool {
+			s := fieldString(field)
+			for _, option := range options {
+				if s == option {
+					return true
+				}
+			}
+			return false
+		}, true, nil
+
+	case "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield":
+		other, ok := t.FieldByName(param)
+		if !ok {
+			return nil, true, fmt.Errorf("zen: rule %s of %s refers to unknown field %q", name, t, param)
+		}
+		return fieldRule(name, other.Index), true, nil
+	}
+	return nil, false, nil
+}
+
+// sizeRule check length of strings, slices and maps, or value of numbers
+func sizeRule(name string, n float64) ruleCheck {
+	return func(field, _ reflect.Value) bool {
+		size, ok := valueSize(field)
+		if !ok {
+			return false
+		}
+		switch name {
+		case "min":
+			return size >= n
+		case "max":
+			return size <= n
+		}
+		return size == n
+	}
+}
+
+// fieldRule compare field with the field at index of the same struct
+func fieldRule(name string, index []int) ruleCheck {
+	return func(field, parent reflect.Value) bool {
+		other := reflect.Indirect(parent.FieldByIndex(index))
+		if !other.IsValid() {
+			return name == "nefield"
+		}
+		cmp, ok := compareValues(field, other)
+		if !ok {
+			equal := reflect.DeepEqual(field.Interface(), other.Interface())
+			switch name {
+			case "eqfield":
+				return equal
+			case "nefield":
+				return !equal
+			}
+			return false
+		}
+		switch name {
+		case "eqfield":
+			return cmp == 0
+		case "nefield":
+			return cmp != 0
+		case "gtfield":
+			return cmp > 0
+		case "gtefield":
+			return cmp >= 0
+		case "ltfield":
+			return cmp < 0
+		}
+		return cmp <= 0
+	}
+}
+
+// valueSize return rune count of strings, length of slices, arrays and maps, or value of numbers
+func valueSize(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// compareValues compare numbers, strings and times, ok is false for other kinds
+func compareValues(a, b reflect.Value) (cmp int, ok bool) {
+	if a.Type() == timeType && b.Type() == timeType {
+		ta, tb := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		}
+		return 0, true
+	}
+	if a.Kind() == reflect.String && b.Kind() == reflect.String {
+		return strings.Compare(a.String(), b.String()), true
+	}
+
+	if !isNumber(a.Kind()) || !isNumber(b.Kind()) {
+		return 0, false
+	}
+	x, _ := valueSize(a)
+	y, _ := valueSize(b)
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+// isNumber report whether k is a kind of integer or float
+func isNumber(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isEmpty report whether v is nil, empty or zero value
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// isEmail report whether s is a bare email address, such as gopher@example.com
+func isEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s && strings.Contains(s[strings.LastIndex(s, "@"):], ".")
+}
+
+// fieldString format v as it would be submitted in a form
+func fieldString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return ""
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	}
+	if v.CanInterface() {
+		return fmt.Sprint(v.Interface())
+	}
+	return ""
+}
@@ -0,0 +1,234 @@
+package zen
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validateAddress struct {
+	City string `valid:"required"`
+}
+
+type validateSignup struct {
+	Name     string   `valid:"required,min=3,max=10"`
+	Mail     string   `valid:"required,email"`
+	Role     string   `valid:"oneof=admin user"`
+	Age      int      `valid:"min=18,max=130"`
+	Tags     []string `valid:"max=2"`
+	Nick     *string  `valid:"omitempty,min=2"`
+	Password string
+	Confirm  string `valid:"eqfield=Password"`
+	Start    time.Time
+	End      time.Time `valid:"gtfield=Start"`
+	Address  *validateAddress
+	Code     string `valid:"^[0-9]{2,4}$" msg:"illegal code"`
+	Even     int    `valid:"even"`
+}
+
+func TestServer_validate(t *testing.T) {
+	now := time.Now()
+	valid := func() validateSignup {
+		return validateSignup{
+			Name: "gopher", Mail: "gopher@example.com", Role: "admin", Age: 20,
+			Password: "secret", Confirm: "secret", Start: now, End: now.Add(time.Hour),
+			Address: &validateAddress{City: "Paris"}, Code: "123",
+		}
+	}
+	nick := "x"
+
+	tests := []struct {
+		name   string
+		modify func(*validateSignup)
+		want   string
+	}{
+		{"valid", func(*validateSignup) {}, ""},
+		{"required", func(v *validateSignup) { v.Name = "" }, "Name does not satisfy required"},
+		{"min length", func(v *validateSignup) { v.Name = "go" }, "Name does not satisfy min=3"},
+		{"max length", func(v *validateSignup) { v.Name = "gophergopher" }, "Name does not satisfy max=10"},
+		{"email", func(v *validateSignup) { v.Mail = "gopher" }, "Mail does not satisfy email"},
+		{"oneof", func(v *validateSignup) { v.Role = "root" }, "Role does not satisfy oneof=admin user"},
+		{"numeric range", func(v *validateSignup) { v.Age = 17 }, "Age does not satisfy min=18"},
+		{"slice length", func(v *validateSignup) { v.Tags = []string{"a", "b", "c"} }, "Tags does not satisfy max=2"},
+		{"omitempty", func(v *validateSignup) { v.Nick = nil }, ""},
+		{"pointer", func(v *validateSignup) { v.Nick = &nick }, "Nick does not satisfy min=2"},
+		{"eqfield", func(v *validateSignup) { v.Confirm = "secrets" }, "Confirm does not satisfy eqfield=Password"},
+		{"gtfield", func(v *validateSignup) { v.End = now }, "End does not satisfy gtfield=Start"},
+		{"nested", func(v *validateSignup) { v.Address.City = "" }, "City does not satisfy required"},
+		{"nil nested", func(v *validateSignup) { v.Address = nil }, ""},
+		{"regex", func(v *validateSignup) { v.Code = "x" }, "illegal code"},
+		{"custom", func(v *validateSignup) { v.Even = 3 }, "Even does not satisfy even"},
+	}
+
+	s := New()
+	s.RegisterValidator("even", func(value interface{}, _ string) bool {
+		return value.(int)%2 == 0
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := valid()
+			tt.modify(&v)
+			err := s.validate(&v)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validate() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_validate_InvalidTag(t *testing.T) {
+	tests := []struct {
+		name  string
+		input interface{}
+	}{
+		{"bad param", &struct {
+			A string `valid:"min=x"`
+		}{}},
+		{"unknown field", &struct {
+			A string `valid:"eqfield=B"`
+		}{}},
+		{"bad regex", &struct {
+			A string `valid:"[a-"`
+		}{}},
+		{"unknown rule", &struct {
+			A string `valid:"required,emial"`
+		}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New().validate(tt.input); err == nil {
+				t.Error("validate() error = nil")
+			}
+		})
+	}
+}
+
+func TestServer_RegisterValidator_Recompile(t *testing.T) {
+	type input struct {
+		A string `valid:"upper"`
+	}
+	s := New()
+	// upper is unknown yet
+	err := s.validate(&input{A: "UP"})
+	if _, ok := err.(ValidationErrors); ok || err == nil {
+		t.Fatalf("validate() error = %v, want unknown rule", err)
+	}
+	s.RegisterValidator("upper", func(value interface{}, _ string) bool {
+		return strings.ToUpper(value.(string)) == value.(string)
+	})
+	if err := s.validate(&input{A: "UP"}); err != nil {
+		t.Errorf("validate() error = %v after registering validator", err)
+	}
+	if err := s.validate(&input{A: "up"}); err == nil {
+		t.Error("validate() error = nil after registering validator")
+	}
+}
+
+func TestServer_validate_Aggregated(t *testing.T) {
+	type address struct {
+		City string `form:"city" valid:"required"`
+	}
+	type input struct {
+		Name    string  `form:"name" valid:"min=3" msg:"name too short"`
+		Age     int     `json:"age" valid:"min=18"`
+		Address address `form:"address"`
+	}
+
+	err := New().validate(&input{Name: "go", Age: 3})
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("validate() error = %#v, want ValidationErrors", err)
+	}
+	want := ValidationErrors{
+		{Field: "Name", Key: "name", Rule: "min", Param: "3", Value: "go", Message: "name too short", messageKey: "name too short"},
+		{Field: "Age", Key: "age", Rule: "min", Param: "18", Value: 3, Message: "Age does not satisfy min=18"},
+		{Field: "Address.City", Key: "address.city", Rule: "required", Value: "", Message: "Address.City does not satisfy required"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		for _, e := range errs {
+			t.Logf("%+v", e)
+		}
+		t.Fatal("validate() errors mismatch")
+	}
+	if got := errs.Error(); got != "name too short; Age does not satisfy min=18; Address.City does not satisfy required" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestContext_ParseValidateForm_TypeErrors(t *testing.T) {
+	type address struct {
+		Zip int `form:"zip"`
+	}
+	type input struct {
+		Age     int     `form:"age" valid:"min=18"`
+		Mail    string  `form:"mail" valid:"email"`
+		Name    string  `form:"name" valid:"required"`
+		Address address `form:"address"`
+	}
+	var in input
+	var err error
+	s := New()
+	s.Route(POST, "/", func(c *Context) {
+		err = c.ParseValidateForm(&in)
+	})
+	req := httptest.NewRequest(POST, "/", strings.NewReader("age=abc&mail=nope&name=zen&address.zip=x"))
+	req.Header.Set(contentType, applicationForm)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("ParseValidateForm() error = %#v, want ValidationErrors", err)
+	}
+	// fields failing to be bound are not checked against their rules
+	want := ValidationErrors{
+		{Field: "Age", Key: "age", Rule: "type", Param: "int", Value: "abc", Message: "Age does not satisfy type=int"},
+		{Field: "Address.Zip", Key: "address.zip", Rule: "type", Param: "int", Value: "x", Message: "Address.Zip does not satisfy type=int"},
+		{Field: "Mail", Key: "mail", Rule: "email", Value: "nope", Message: "Mail does not satisfy email"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		for _, e := range errs {
+			t.Logf("%+v", e)
+		}
+		t.Fatal("ParseValidateForm() errors mismatch")
+	}
+	if in.Name != "zen" {
+		t.Errorf("Name = %q, remaining fields are not bound", in.Name)
+	}
+}
+
+func TestContext_ValidationProblem(t *testing.T) {
+	type input struct {
+		Name string `form:"name" valid:"required"`
+	}
+	s := New()
+	s.Route(POST, "/", func(c *Context) {
+		var in input
+		if errs, ok := c.Bind(&in).(ValidationErrors); ok {
+			c.ValidationProblem(errs)
+		}
+	})
+	req := httptest.NewRequest(POST, "/", strings.NewReader("name="))
+	req.Header.Set(contentType, applicationForm)
+	rw := httptest.NewRecorder()
+	s.ServeHTTP(rw, req)
+
+	if rw.Code != http.StatusUnprocessableEntity {
+		t.Errorf("code = %d", rw.Code)
+	}
+	if ct := rw.Header().Get(contentType); ct != applicationProblemJSON {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	want := `{"type":"about:blank","title":"Unprocessable Entity","status":422,"errors":[{"field":"Name","key":"name","rule":"required","value":"","message":"Name does not satisfy required"}]}`
+	if got := strings.TrimSpace(rw.Body.String()); got != want {
+		t.Errorf("body = %s", got)
+	}
+}
@@ -1,8 +1,12 @@
 package zen
 
 import (
+	"net"
 	"net/http"
+	"reflect"
 	"sync"
+	"text/template"
+	"time"
 )
 
 const (
@@ -16,25 +20,65 @@ type (
 		routeTree       []*methodNode
 		notFoundHandler HandlerFunc
 		panicHandler    PanicHandler
+		timeoutHandler  HandlerFunc
 		filters         []HandlerFunc
 		contextPool     sync.Pool
+
+		maxDecompressedSize int64
+		maxBodyBytes        int64
+		routeBodyLimits     map[routeKey]int64
+		maxMultipartMemory  int64
+		maxFileSize         int64
+		streamFlushInterval time.Duration
+		timeout             time.Duration
+		useRawPath          bool
+		loadShedder         *LoadShedder
+		trustedProxies      []*net.IPNet
+		renderers           []namedRenderer
+		binders             map[string]Binder
+		validators          map[string]ValidatorFunc
+		validationRules     map[reflect.Type]*structRules
+		validationMu        sync.RWMutex
+		messages            map[string]map[string]*template.Template
+		defaultLocale       string
+		localeResolver      LocaleResolver
 	}
 )
 
 // New will create a Server instance and return a pointer which point to it
 func New() *Server {
 
-	s := &Server{contextPool: sync.Pool{}, filters: []HandlerFunc{}}
+	s := &Server{
+		contextPool:         sync.Pool{},
+		filters:             []HandlerFunc{},
+		maxDecompressedSize: defaultMaxDecompressedSize,
+		maxMultipartMemory:  defaultMaxMultipartMemory,
+		validators:          make(map[string]ValidatorFunc),
+		validationRules:     make(map[reflect.Type]*structRules),
+		defaultLocale:       defaultLocale,
+		streamFlushInterval: defaultStreamFlushInterval,
+	}
 	s.contextPool.New = func() interface{} {
 		c := Context{rw: &responseWriter{}}
 		return &c
 	}
+	s.registerDefaultRenderers()
+	s.registerDefaultBinders()
 	return s
 }
 
 // Required by http.Handler interface. This method is invoked by the
 // http server and will handle all page routing
 func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// shed request before any work if server is overloaded
+	if l := s.loadShedder; l != nil {
+		if !l.acquire(r.Context()) {
+			l.shed(rw)
+			return
+		}
+		defer l.release(time.Now())
+	}
+
 	// get context instance from pool
 	c := s.getContext(rw, r)
 
@@ -49,34 +93,57 @@ func (s *Server) handleHTTPRequest(c *Context) {
 	defer s.handlePanic(c)
 
 	httpMethod := c.Req.Method
-	path := c.Req.URL.Path
+	path, escaped := c.Req.URL.Path, false
+	if s.useRawPath && c.Req.URL.RawPath != "" {
+		path, escaped = c.Req.URL.RawPath, true
+	}
 
 	for i := 0; i < len(s.routeTree); i++ {
 		t := s.routeTree[i]
 		if t.method == httpMethod {
-			handlers, params := t.node.get(path, c.params)
+			handlers, params, fullPath := t.node.get(path, c.params)
 			c.params = params
-
-			for _, h := range s.filters {
-				h(c)
-				if c.rw.written {
-					return
-				}
+			// route specific body limit applies to filters too
+			if n, ok := s.routeBodyLimits[routeKey{httpMethod, fullPath}]; ok && handlers != nil {
+				c.maxBodyBytes = n
 			}
-
-			for _, h := range handlers {
-				h(c)
-				if c.rw.written {
-					return
-				}
+			if escaped {
+				unescapeParams(c.params)
 			}
 
+			if s.timeout > 0 {
+				s.runWithTimeout(c, s.timeout, func(c *Context) {
+					s.runHandlers(c, handlers)
+				})
+			} else {
+				s.runHandlers(c, handlers)
+			}
+			if c.rw.written {
+				return
+			}
 		}
 	}
 
 	s.handleNotFound(c)
 }
 
+// runHandlers run server's filters and handlers in order, until one of them writes response
+func (s *Server) runHandlers(c *Context, handlers Handlers) {
+	for _, h := range s.filters {
+		h(c)
+		if c.rw.written {
+			return
+		}
+	}
+
+	for _, h := range handlers {
+		h(c)
+		if c.rw.written {
+			return
+		}
+	}
+}
+
 // Run server on addr
 func (s *Server) Run(addr string) error {
 	return http.ListenAndServe(addr, s)